@@ -1,95 +1,175 @@
 package app
 
 import (
-	"crypto/sha256"
-	"crypto/subtle"
-	"encoding/hex"
+	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
 	"strings"
-
-	"golang.org/x/exp/slog"
+	"time"
 )
 
 type ApiKeyConfig struct {
 	APIKeyHeader string
-	APIKeys      map[string]string
+	// APIKeys maps key names to hex-encoded SHA-256 hashes.
+	// Ignored when Store is set.
+	APIKeys map[string]string
+	// APIKeyScopes maps key names to the scopes they are granted.
+	// Ignored when Store is set.
+	APIKeyScopes map[string][]string
+	// APIKeySpecs defines keys with scopes and validity windows, in addition
+	// to APIKeys. Ignored when Store is set.
+	APIKeySpecs map[string]APIKeySpec
+	// APIKeyMaxLen rejects keys longer than this before they are hashed.
+	// Defaults to DefaultAPIKeyMaxLen.
 	APIKeyMaxLen int
+	// Extractors are tried in order to find the key in the request; the first
+	// non-empty result is used. Defaults to the raw value of APIKeyHeader.
+	//
+	// Example:
+	//
+	//	Extractors: []app.TokenExtractor{
+	//	    app.TokenFromHeader("X-API-KEY"),
+	//	    app.TokenFromAuthScheme("ApiKey"),
+	//	    app.TokenFromQuery("api_key"),
+	//	}
+	Extractors []TokenExtractor
+	// Store looks up keys at request time, allowing keys to be rotated
+	// without restarting the app. Defaults to an in-memory store built from APIKeys.
+	Store APIKeyStore
+	// MaxKeyAge rejects keys created longer ago than this (e.g. 90 days) to
+	// enforce rotation. Zero disables the check.
+	MaxKeyAge time.Duration
 }
 
-func ApiKeyMiddleware(cfg ApiKeyConfig) (func(handler http.Handler) http.Handler, error) {
-	apiKeyHeader := cfg.APIKeyHeader
-	apiKeys := cfg.APIKeys
-	// apiKeyMaxLen := cfg.APIKeyMaxLen
+// DefaultAPIKeyMaxLen is the default maximum accepted API key length
+const DefaultAPIKeyMaxLen = 512
+
+// ApiKeyAuthenticator authenticates requests with API keys.
+// Create it with NewApiKeyAuthenticator.
+type ApiKeyAuthenticator struct {
+	store      APIKeyStore
+	extractors []TokenExtractor
+	maxLen     int
+	maxKeyAge  time.Duration
+}
+
+// NewApiKeyAuthenticator creates an Authenticator from the API key configuration
+func NewApiKeyAuthenticator(cfg ApiKeyConfig) (*ApiKeyAuthenticator, error) {
+	apiKeyMaxLen := cfg.APIKeyMaxLen
+	if apiKeyMaxLen <= 0 {
+		apiKeyMaxLen = DefaultAPIKeyMaxLen
+	}
+
+	extractors := cfg.Extractors
+	if len(extractors) == 0 {
+		extractors = []TokenExtractor{TokenFromHeader(cfg.APIKeyHeader)}
+	}
 
-	decodedAPIKeys := make(map[string][]byte)
-	for name, value := range apiKeys {
-		decodedKey, err := hex.DecodeString(value)
+	store := cfg.Store
+	if store == nil {
+		specs := make(map[string]APIKeySpec, len(cfg.APIKeys)+len(cfg.APIKeySpecs))
+		for name, hash := range cfg.APIKeys {
+			specs[name] = APIKeySpec{Hash: hash, Scopes: cfg.APIKeyScopes[name]}
+		}
+		for name, spec := range cfg.APIKeySpecs {
+			specs[name] = spec
+		}
+		keys, err := DecodeAPIKeySpecs(specs)
 		if err != nil {
 			return nil, err
 		}
-
-		decodedAPIKeys[name] = decodedKey
+		memStore := &MemoryAPIKeyStore{}
+		memStore.Set(keys)
+		store = memStore
 	}
 
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx := r.Context()
-
-			apiKey, err := apiToken(r, apiKeyHeader)
-			if err != nil {
-				slog.Error("request failed API key authentication", "error", err)
-				http.Error(w, "invalid API key", http.StatusUnauthorized)
-				return
-			}
-
-			if _, ok := apiKeyIsValid(apiKey, decodedAPIKeys); !ok {
-				hostIP, _, err := net.SplitHostPort(r.RemoteAddr)
-				if err != nil {
-					slog.Error("failed to parse remote address", "error", err)
-					hostIP = r.RemoteAddr
-				}
-				slog.Error("no matching API key found", "remoteIP", hostIP)
-
-				http.Error(w, "invalid api key", http.StatusUnauthorized)
-				return
-			}
-
-			next.ServeHTTP(w, r.WithContext(ctx))
-		})
+	return &ApiKeyAuthenticator{
+		store:      store,
+		extractors: extractors,
+		maxLen:     apiKeyMaxLen,
+		maxKeyAge:  cfg.MaxKeyAge,
 	}, nil
 }
 
-// apiKeyIsValid checks if the given API key is valid and returns the principal if it is.
-func apiKeyIsValid(rawKey string, availableKeys map[string][]byte) (string, bool) {
-	hash := sha256.Sum256([]byte(rawKey))
-	key := hash[:]
+// Name implements Authenticator
+func (a *ApiKeyAuthenticator) Name() string {
+	return "apikey"
+}
+
+// Authenticate implements Authenticator
+func (a *ApiKeyAuthenticator) Authenticate(r *http.Request) (context.Context, error) {
+	ctx := r.Context()
 
-	for name, value := range availableKeys {
-		contentEqual := subtle.ConstantTimeCompare(value, key) == 1
+	apiKey := extractToken(r, a.extractors)
+	if apiKey == "" {
+		return nil, &AuthError{Method: a.Name(), Reason: AuthFailureMissing}
+	}
 
-		if contentEqual {
-			return name, true
-		}
+	if len(apiKey) > a.maxLen {
+		return nil, &AuthError{Method: a.Name(), Reason: AuthFailureTooLong,
+			Err: fmt.Errorf("key length %d exceeds %d", len(apiKey), a.maxLen)}
 	}
 
-	return "", false
+	key, ok := a.store.Lookup(apiKey)
+	if !ok {
+		return nil, &AuthError{Method: a.Name(), Reason: AuthFailureUnknown,
+			Err: errors.New("no matching API key found")}
+	}
+
+	if reason := key.Check(time.Now(), a.maxKeyAge); reason != "" {
+		return nil, &AuthError{Method: a.Name(), Reason: reason, Principal: key.Name}
+	}
+
+	ctx = setPrincipal(ctx, key.Name)
+	ctx = WithScopes(ctx, key.Scopes)
+	return ctx, nil
+}
+
+// ApiKeyMiddleware returns a middleware that only lets through requests with a valid API key
+func ApiKeyMiddleware(cfg ApiKeyConfig) (func(handler http.Handler) http.Handler, error) {
+	a, err := NewApiKeyAuthenticator(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return Authenticate(a), nil
+}
+
+// remoteIP returns the host part of the request's remote address
+func remoteIP(r *http.Request) string {
+	hostIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		slog.Error("failed to parse remote address", "error", err)
+		return r.RemoteAddr
+	}
+	return hostIP
 }
 
 // bearerToken extracts the content from the header, striping the Bearer prefix
 func bearerToken(r *http.Request, header string) (string, error) {
+	return schemeToken(r, header, "Bearer")
+}
+
+// schemeToken extracts the content from the header, striping the given
+// authentication scheme prefix (matched case-insensitively)
+func schemeToken(r *http.Request, header, scheme string) (string, error) {
 	if header == "" {
 		// header = "X-API-KEY"
 		header = "Authorization"
 	}
-	rawToken := r.Header.Get(header)
+	rawToken := strings.TrimSpace(r.Header.Get(header))
 	pieces := strings.SplitN(rawToken, " ", 2)
 
 	if len(pieces) < 2 {
 		return "", errors.New("token with incorrect bearer format")
 	}
 
+	if !strings.EqualFold(pieces[0], scheme) {
+		return "", fmt.Errorf("token with unexpected scheme %q", pieces[0])
+	}
+
 	token := strings.TrimSpace(pieces[1])
 
 	return token, nil
@@ -0,0 +1,339 @@
+package app
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// APIKey describes a single API key known to an APIKeyStore
+type APIKey struct {
+	// Name identifies the key owner and becomes the request principal
+	Name string
+	// Hash is the SHA-256 digest of the raw key
+	Hash []byte
+	// Scopes lists the permissions granted to the key (see RequireScopes)
+	Scopes []string
+
+	// CreatedAt is when the key was issued; used with ApiKeyConfig.MaxKeyAge
+	CreatedAt time.Time
+	// NotBefore is the time before which the key is not accepted
+	NotBefore time.Time
+	// ExpiresAt is the time from which the key is no longer accepted
+	ExpiresAt time.Time
+	// Revoked disables the key regardless of its validity window
+	Revoked bool
+}
+
+// API key authentication failure reasons, used in logs and the
+// http_auth_failures_total metric
+const (
+	AuthFailureMissing     = "missing"
+	AuthFailureTooLong     = "too_long"
+	AuthFailureUnknown     = "unknown"
+	AuthFailureRevoked     = "revoked"
+	AuthFailureExpired     = "expired"
+	AuthFailureNotYetValid = "not_yet_valid"
+)
+
+// Check reports why the key cannot be used at the given time, or "" if it is
+// valid. A non-zero maxAge treats keys created more than maxAge ago as expired;
+// keys without CreatedAt are not subject to maxAge.
+func (k *APIKey) Check(now time.Time, maxAge time.Duration) string {
+	switch {
+	case k.Revoked:
+		return AuthFailureRevoked
+	case !k.NotBefore.IsZero() && now.Before(k.NotBefore):
+		return AuthFailureNotYetValid
+	case !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt):
+		return AuthFailureExpired
+	case maxAge > 0 && !k.CreatedAt.IsZero() && now.Sub(k.CreatedAt) > maxAge:
+		return AuthFailureExpired
+	}
+	return ""
+}
+
+// APIKeySpec is the serialized form of an API key in key files.
+// In JSON and YAML it may be written either as a plain hex hash string or
+// as an object:
+//
+//	{
+//	  "hash": "ba7816bf...",
+//	  "scopes": ["orders:read"],
+//	  "created_at": "2025-01-01T00:00:00Z",
+//	  "not_before": "2025-01-02T00:00:00Z",
+//	  "expires_at": "2025-04-01T00:00:00Z",
+//	  "revoked": false
+//	}
+type APIKeySpec struct {
+	Hash      string    `json:"hash" yaml:"hash"`
+	Scopes    []string  `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero" yaml:"created_at,omitempty"`
+	NotBefore time.Time `json:"not_before,omitzero" yaml:"not_before,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitzero" yaml:"expires_at,omitempty"`
+	Revoked   bool      `json:"revoked,omitempty" yaml:"revoked,omitempty"`
+}
+
+// UnmarshalJSON accepts either a hash string or an object
+func (s *APIKeySpec) UnmarshalJSON(data []byte) error {
+	var hash string
+	if err := json.Unmarshal(data, &hash); err == nil {
+		*s = APIKeySpec{Hash: hash}
+		return nil
+	}
+
+	type plain APIKeySpec
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// UnmarshalYAML accepts either a hash string or a mapping
+func (s *APIKeySpec) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*s = APIKeySpec{Hash: value.Value}
+		return nil
+	}
+
+	type plain APIKeySpec
+	return value.Decode((*plain)(s))
+}
+
+// APIKeyStore looks up API keys presented by clients.
+// Implementations must be safe for concurrent use.
+type APIKeyStore interface {
+	// Lookup returns the key matching the raw (unhashed) key, if any
+	Lookup(rawKey string) (*APIKey, bool)
+}
+
+// DecodeAPIKeys converts a map of name → hex-encoded SHA-256 hash into API keys
+func DecodeAPIKeys(keys map[string]string) ([]APIKey, error) {
+	specs := make(map[string]APIKeySpec, len(keys))
+	for name, hash := range keys {
+		specs[name] = APIKeySpec{Hash: hash}
+	}
+	return DecodeAPIKeySpecs(specs)
+}
+
+// DecodeAPIKeySpecs converts a map of name → key spec into API keys
+func DecodeAPIKeySpecs(specs map[string]APIKeySpec) ([]APIKey, error) {
+	decoded := make([]APIKey, 0, len(specs))
+	for name, spec := range specs {
+		hash, err := hex.DecodeString(strings.TrimSpace(spec.Hash))
+		if err != nil {
+			return nil, fmt.Errorf("api key %q: %w", name, err)
+		}
+		if len(hash) != sha256.Size {
+			return nil, fmt.Errorf("api key %q: expected %d byte sha256 hash, got %d", name, sha256.Size, len(hash))
+		}
+		decoded = append(decoded, APIKey{
+			Name:      name,
+			Hash:      hash,
+			Scopes:    spec.Scopes,
+			CreatedAt: spec.CreatedAt,
+			NotBefore: spec.NotBefore,
+			ExpiresAt: spec.ExpiresAt,
+			Revoked:   spec.Revoked,
+		})
+	}
+	return decoded, nil
+}
+
+// MemoryAPIKeyStore is an in-memory APIKeyStore.
+// The key set can be swapped atomically with Set.
+type MemoryAPIKeyStore struct {
+	keys atomic.Pointer[[]APIKey]
+}
+
+// NewMemoryAPIKeyStore creates an in-memory store from a map of
+// name → hex-encoded SHA-256 hash
+func NewMemoryAPIKeyStore(keys map[string]string) (*MemoryAPIKeyStore, error) {
+	decoded, err := DecodeAPIKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &MemoryAPIKeyStore{}
+	s.Set(decoded)
+	return s, nil
+}
+
+// Set atomically replaces all keys in the store
+func (s *MemoryAPIKeyStore) Set(keys []APIKey) {
+	s.keys.Store(&keys)
+}
+
+// Lookup implements APIKeyStore
+func (s *MemoryAPIKeyStore) Lookup(rawKey string) (*APIKey, bool) {
+	keys := s.keys.Load()
+	if keys == nil {
+		return nil, false
+	}
+	return apiKeyIsValid(rawKey, *keys)
+}
+
+// Len returns the number of keys in the store
+func (s *MemoryAPIKeyStore) Len() int {
+	keys := s.keys.Load()
+	if keys == nil {
+		return 0
+	}
+	return len(*keys)
+}
+
+// FileAPIKeyStore is an APIKeyStore backed by a JSON or YAML file mapping
+// key names to hex-encoded SHA-256 hashes or APIKeySpec objects:
+//
+//	{
+//	  "service-a": "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+//	  "service-b": {"hash": "ba7816bf...", "scopes": ["orders:read"]}
+//	}
+//
+// The file is polled for changes and reloaded atomically. If a reload fails
+// the previous keys stay in effect.
+type FileAPIKeyStore struct {
+	path    string
+	keys    MemoryAPIKeyStore
+	watcher *fileWatcher
+}
+
+// NewFileAPIKeyStore loads keys from path and watches it for changes.
+// Files ending in .yaml or .yml are parsed as YAML, everything else as JSON.
+// A pollInterval of zero uses DefaultFileWatchInterval.
+func NewFileAPIKeyStore(path string, pollInterval time.Duration) (*FileAPIKeyStore, error) {
+	s := &FileAPIKeyStore{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	s.watcher = watchFiles(pollInterval, func() {
+		if err := s.Reload(); err != nil {
+			slog.Error("Failed to reload API keys", "path", s.path, "err", err)
+		}
+	}, path)
+
+	return s, nil
+}
+
+// Reload reads the key file and replaces the current keys
+func (s *FileAPIKeyStore) Reload() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("read api key file: %w", err)
+	}
+
+	raw := make(map[string]APIKeySpec)
+	switch strings.ToLower(filepath.Ext(s.path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		err = json.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return fmt.Errorf("parse api key file %s: %w", s.path, err)
+	}
+
+	keys, err := DecodeAPIKeySpecs(raw)
+	if err != nil {
+		return fmt.Errorf("parse api key file %s: %w", s.path, err)
+	}
+
+	s.keys.Set(keys)
+	slog.Info("API keys loaded", "path", s.path, "count", len(keys))
+	return nil
+}
+
+// Lookup implements APIKeyStore
+func (s *FileAPIKeyStore) Lookup(rawKey string) (*APIKey, bool) {
+	return s.keys.Lookup(rawKey)
+}
+
+// Close stops watching the key file
+func (s *FileAPIKeyStore) Close() error {
+	if s.watcher != nil {
+		s.watcher.stopWatching()
+	}
+	return nil
+}
+
+// DefaultAPIKeyEnvPrefix is the default environment variable prefix for EnvAPIKeyStore
+const DefaultAPIKeyEnvPrefix = "API_KEY_"
+
+// EnvAPIKeyStore is an APIKeyStore backed by environment variables.
+// Each variable named <prefix><NAME> holds the hex-encoded SHA-256 hash of a
+// key, optionally followed by a colon and a comma-separated scope list; the
+// principal name is NAME in lower case. For example,
+// API_KEY_SERVICE_A=ba78...:orders:read,orders:write registers the key
+// "service_a" with two scopes.
+type EnvAPIKeyStore struct {
+	prefix string
+	keys   MemoryAPIKeyStore
+}
+
+// NewEnvAPIKeyStore loads keys from environment variables with the given prefix.
+// An empty prefix uses DefaultAPIKeyEnvPrefix.
+func NewEnvAPIKeyStore(prefix string) (*EnvAPIKeyStore, error) {
+	if prefix == "" {
+		prefix = DefaultAPIKeyEnvPrefix
+	}
+
+	s := &EnvAPIKeyStore{prefix: prefix}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload re-reads the environment and replaces the current keys. Variables
+// with the prefix whose value is not a valid hash (e.g. API_KEY_HEADER) are
+// logged and skipped.
+func (s *EnvAPIKeyStore) Reload() error {
+	var keys []APIKey
+	for _, kv := range os.Environ() {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, s.prefix) || len(name) == len(s.prefix) {
+			continue
+		}
+
+		hash, scopes, _ := strings.Cut(value, ":")
+		decoded, err := DecodeAPIKeySpecs(map[string]APIKeySpec{
+			strings.ToLower(strings.TrimPrefix(name, s.prefix)): {Hash: hash, Scopes: splitScopes(scopes)},
+		})
+		if err != nil {
+			slog.Warn("Ignoring environment variable that is not an API key hash", "name", name, "err", err)
+			continue
+		}
+		keys = append(keys, decoded...)
+	}
+
+	s.keys.Set(keys)
+	return nil
+}
+
+// Lookup implements APIKeyStore
+func (s *EnvAPIKeyStore) Lookup(rawKey string) (*APIKey, bool) {
+	return s.keys.Lookup(rawKey)
+}
+
+// apiKeyIsValid checks if the given API key is valid and returns the matching key if it is.
+// Every key is compared in constant time so the lookup does not leak which key matched.
+func apiKeyIsValid(rawKey string, availableKeys []APIKey) (*APIKey, bool) {
+	hash := sha256.Sum256([]byte(rawKey))
+
+	var match *APIKey
+	for i := range availableKeys {
+		if subtle.ConstantTimeCompare(availableKeys[i].Hash, hash[:]) == 1 {
+			match = &availableKeys[i]
+		}
+	}
+
+	return match, match != nil
+}
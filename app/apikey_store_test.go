@@ -0,0 +1,32 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestEnvAPIKeyStoreSkipsInvalidEntries(t *testing.T) {
+	hash := sha256.Sum256([]byte("secret"))
+	t.Setenv("TEST_KEY_SERVICE_A", hex.EncodeToString(hash[:])+":orders:read")
+	t.Setenv("TEST_KEY_HEADER", "X-API-KEY")
+
+	store, err := NewEnvAPIKeyStore("TEST_KEY_")
+	if err != nil {
+		t.Fatalf("NewEnvAPIKeyStore: %v", err)
+	}
+
+	key, ok := store.Lookup("secret")
+	if !ok {
+		t.Fatal("valid key not found")
+	}
+	if key.Name != "service_a" {
+		t.Errorf("Name = %q, want %q", key.Name, "service_a")
+	}
+	if len(key.Scopes) != 1 || key.Scopes[0] != "orders:read" {
+		t.Errorf("Scopes = %v, want [orders:read]", key.Scopes)
+	}
+	if _, ok := store.Lookup("X-API-KEY"); ok {
+		t.Error("invalid entry was loaded as a key")
+	}
+}
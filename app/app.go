@@ -1,8 +1,10 @@
 package app
 
 import (
+	"context"
 	"log/slog"
 
+	httpin_core "github.com/ggicci/httpin/core"
 	httpin_integration "github.com/ggicci/httpin/integration"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/httplog/v2"
@@ -14,11 +16,14 @@ import (
 
 // App represents the application with its router, configuration, and middleware stack
 type App struct {
-	R               *chi.Mux
-	Config          AppConfig
-	Logger          *slog.Logger
-	HTTPLogger      *httplog.Logger
+	R          *chi.Mux
+	Config     AppConfig
+	Logger     *slog.Logger
+	HTTPLogger *httplog.Logger
+	// Health holds the readiness checks served by App.RegisterHealthzRoutes
+	Health          *HealthRegistry
 	middlewareStack *MiddlewareStack
+	lifecycle       lifecycle
 
 	// Internal configuration (not directly exposed)
 	corsOptions     *cors.Options
@@ -51,6 +56,7 @@ func DefaultCorsOptions() *cors.Options {
 func NewApp(opts ...Option) *App {
 	app := &App{
 		R:               chi.NewRouter(),
+		Health:          NewHealthRegistry(),
 		Config:          DefaultAppConfig(),
 		middlewareStack: DefaultMiddlewareStack().Build(),
 	}
@@ -63,6 +69,12 @@ func NewApp(opts ...Option) *App {
 	// Configure httpin if enabled
 	if app.Config.UseHttpin {
 		httpin_integration.UseGochiURLParam("path", chi.URLParam)
+		httpin_core.RegisterErrorHandler(HttpinErrorHandler)
+	}
+
+	// Record the error format for RenderError; must run before any middleware that renders errors
+	if app.Config.ErrorFormat == ErrorFormatProblem {
+		app.R.Use(errorFormatMiddleware(app.Config.ErrorFormat))
 	}
 
 	// Apply the middleware stack to the router
@@ -70,6 +82,10 @@ func NewApp(opts ...Option) *App {
 		app.middlewareStack.Apply(app.R)
 	}
 
+	// Respond to unknown routes with the standard JSON error body
+	app.R.NotFound(NotFoundHandler)
+	app.R.MethodNotAllowed(MethodNotAllowedHandler)
+
 	// Log version information
 	slog.Info("Application initialized", "commit", Commit, "timestamp", Timestamp)
 
@@ -103,3 +119,11 @@ func (app *App) Run() error {
 	srv := &Server{App: app}
 	return srv.Run()
 }
+
+// RunContext starts the HTTP server and blocks until ctx is cancelled or a
+// server fails, then shuts down gracefully. Unlike Run it ignores OS
+// signals; use Server.Start directly to learn the bound address.
+func (app *App) RunContext(ctx context.Context) error {
+	srv := &Server{App: app}
+	return srv.RunContext(ctx)
+}
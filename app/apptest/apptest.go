@@ -0,0 +1,70 @@
+// Package apptest provides helpers for testing applications built with
+// app.NewApp: it serves the app on an httptest.Server, offers fluent request
+// and assertion helpers and records the logs the app emits.
+//
+// Example:
+//
+//	func TestHello(t *testing.T) {
+//	    ta := apptest.New(t, app.WithRateLimit(app.RateLimitConfig{Limit: app.PerMinute(1)}))
+//	    ta.R.Get("/hello", handleHello)
+//
+//	    ta.GET("/hello").Do().
+//	        ExpectStatus(http.StatusOK).
+//	        ExpectJSON(`{"message": "hello"}`)
+//
+//	    ta.GET("/hello").Do().
+//	        ExpectStatus(http.StatusTooManyRequests).
+//	        ExpectErrorCode(app.CodeTooManyRequests)
+//	    ta.Logs.Expect(t, slog.LevelWarn, "Rate limit exceeded")
+//	}
+package apptest
+
+import (
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tendant/chi-demo/app"
+)
+
+// TestApp is an App served by an httptest.Server
+type TestApp struct {
+	*app.App
+
+	// Server serves App.R; it is closed when the test ends
+	Server *httptest.Server
+	// Logs records everything logged through the app logger and the default
+	// slog logger, which the built-in middleware uses
+	Logs *LogRecorder
+
+	t testing.TB
+}
+
+// New builds an App from opts and serves it on an httptest.Server. The app
+// logger is replaced by a LogRecorder unless opts contain app.WithLogger.
+// Routes can be registered on the returned app's router at any time.
+//
+// New sets the default slog logger and restores it when the test ends, so
+// tests using it must not run in parallel. Lifecycle hooks do not run; use
+// app.Server.Start with port 0 to test them.
+func New(t testing.TB, opts ...app.Option) *TestApp {
+	t.Helper()
+
+	prevLogger := slog.Default()
+	logs := NewLogRecorder()
+
+	a := app.NewApp(append([]app.Option{app.WithLogger(slog.New(logs))}, opts...)...)
+	srv := httptest.NewServer(a.R)
+
+	t.Cleanup(func() {
+		srv.Close()
+		slog.SetDefault(prevLogger)
+	})
+
+	return &TestApp{App: a, Server: srv, Logs: logs, t: t}
+}
+
+// URL returns the absolute URL of path on the test server
+func (ta *TestApp) URL(path string) string {
+	return ta.Server.URL + path
+}
@@ -0,0 +1,267 @@
+package apptest_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tendant/chi-demo/app"
+	"github.com/tendant/chi-demo/app/apptest"
+)
+
+func hashKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// expectAuthFailure asserts the log record of the last rejected request
+func expectAuthFailure(t *testing.T, ta *apptest.TestApp, method, reason string) {
+	t.Helper()
+	rec := ta.Logs.Expect(t, slog.LevelError, "request failed authentication")
+	if rec.Attrs["method"] != method || rec.Attrs["reason"] != reason {
+		t.Errorf("auth failure logged as %v/%v, want %s/%s", rec.Attrs["method"], rec.Attrs["reason"], method, reason)
+	}
+	ta.Logs.Reset()
+}
+
+func TestAPIKeyValidity(t *testing.T) {
+	now := time.Now()
+	auth, err := app.NewApiKeyAuthenticator(app.ApiKeyConfig{
+		APIKeyHeader: "X-API-KEY",
+		APIKeySpecs: map[string]app.APIKeySpec{
+			"valid":   {Hash: hashKey("valid-key"), ExpiresAt: now.Add(time.Hour)},
+			"expired": {Hash: hashKey("expired-key"), ExpiresAt: now.Add(-time.Minute)},
+			"future":  {Hash: hashKey("future-key"), NotBefore: now.Add(time.Hour)},
+			"revoked": {Hash: hashKey("revoked-key"), Revoked: true},
+			"old":     {Hash: hashKey("old-key"), CreatedAt: now.Add(-48 * time.Hour)},
+		},
+		MaxKeyAge: 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ta := apptest.New(t)
+	ta.R.With(app.Authenticate(auth)).Get("/orders", ok)
+
+	ta.GET("/orders").Header("X-API-KEY", "valid-key").Do().
+		ExpectStatus(http.StatusOK).
+		ExpectBodyContains("valid")
+
+	tests := []struct {
+		key    string
+		reason string
+	}{
+		{"wrong-key", app.AuthFailureUnknown},
+		{"expired-key", app.AuthFailureExpired},
+		{"old-key", app.AuthFailureExpired},
+		{"future-key", app.AuthFailureNotYetValid},
+		{"revoked-key", app.AuthFailureRevoked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			ta.Logs.Reset()
+			ta.GET("/orders").Header("X-API-KEY", tt.key).Do().
+				ExpectStatus(http.StatusUnauthorized).
+				ExpectErrorCode(app.CodeUnauthorized)
+			expectAuthFailure(t, ta, "apikey", tt.reason)
+		})
+	}
+}
+
+func TestAPIKeyExtraction(t *testing.T) {
+	auth, err := app.NewApiKeyAuthenticator(app.ApiKeyConfig{
+		APIKeys:      map[string]string{"service": hashKey("service-key")},
+		APIKeyMaxLen: 16,
+		Extractors: []app.TokenExtractor{
+			app.TokenFromHeader("X-API-KEY"),
+			app.TokenFromAuthScheme("ApiKey"),
+			app.TokenFromQuery("api_key"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ta := apptest.New(t)
+	ta.R.With(app.Authenticate(auth)).Get("/orders", ok)
+
+	ta.GET("/orders").Header("X-API-KEY", "service-key").Do().ExpectStatus(http.StatusOK)
+	ta.GET("/orders").Header("Authorization", "ApiKey service-key").Do().ExpectStatus(http.StatusOK)
+	ta.GET("/orders?api_key=service-key").Do().ExpectStatus(http.StatusOK)
+
+	// The first extractor that finds a key wins
+	ta.GET("/orders?api_key=service-key").Header("X-API-KEY", "wrong-key").Do().
+		ExpectStatus(http.StatusUnauthorized)
+	expectAuthFailure(t, ta, "apikey", app.AuthFailureUnknown)
+
+	ta.GET("/orders").Do().
+		ExpectStatus(http.StatusUnauthorized)
+	expectAuthFailure(t, ta, "apikey", app.AuthFailureMissing)
+
+	ta.GET("/orders").Header("X-API-KEY", strings.Repeat("k", 17)).Do().
+		ExpectStatus(http.StatusUnauthorized).
+		ExpectErrorCode(app.CodeUnauthorized)
+	expectAuthFailure(t, ta, "apikey", app.AuthFailureTooLong)
+}
+
+func TestRequireScopes(t *testing.T) {
+	mw, err := app.ApiKeyMiddleware(app.ApiKeyConfig{
+		APIKeyHeader: "X-API-KEY",
+		APIKeys:      map[string]string{"reader": hashKey("reader-key"), "writer": hashKey("writer-key")},
+		APIKeyScopes: map[string][]string{"reader": {"orders:read"}, "writer": {"orders:read", "orders:write"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ta := apptest.New(t)
+	// Without authentication in front, RequireScopes has no principal
+	ta.R.With(app.RequireScopes("orders:write")).Post("/unauthenticated", ok)
+	ta.R.With(mw, app.RequireScopes("orders:write")).Post("/orders", ok)
+
+	ta.POST("/unauthenticated").Do().
+		ExpectStatus(http.StatusUnauthorized).
+		ExpectErrorCode(app.CodeUnauthorized)
+
+	var body app.APIError
+	ta.POST("/orders").Header("X-API-KEY", "reader-key").Do().
+		ExpectStatus(http.StatusForbidden).
+		ExpectErrorCode(app.CodeForbidden).
+		DecodeJSON(&body)
+	if len(body.Errors) != 1 || body.Errors[0].Code != "missing_scope" {
+		t.Errorf("errors = %+v, want one missing_scope error", body.Errors)
+	}
+	rec := ta.Logs.Expect(t, slog.LevelWarn, "Request rejected: missing scope")
+	if rec.Attrs["principal"] != "reader" {
+		t.Errorf("principal = %v, want reader", rec.Attrs["principal"])
+	}
+
+	ta.POST("/orders").Header("X-API-KEY", "writer-key").Do().
+		ExpectStatus(http.StatusOK)
+}
+
+// hmacHeaders signs a request to path with the given body and returns its headers
+func hmacHeaders(t *testing.T, method, path, body, nonce string) http.Header {
+	t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if err := app.SignRequest(r, "partner", "s3cret", nonce); err != nil {
+		t.Fatal(err)
+	}
+	return r.Header
+}
+
+func withHeaders(req *apptest.Request, header http.Header) *apptest.Request {
+	for key := range header {
+		req.Header(key, header.Get(key))
+	}
+	return req
+}
+
+func TestAnyOfReportedFailure(t *testing.T) {
+	apiKeyAuth, err := app.NewApiKeyAuthenticator(app.ApiKeyConfig{
+		APIKeyHeader: "X-API-KEY",
+		APIKeys:      map[string]string{"service": hashKey("service-key")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmacAuth, err := app.NewHMACAuthenticator(app.HMACConfig{Secrets: map[string]string{"partner": "s3cret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ta := apptest.New(t)
+	ta.R.With(app.AnyOf(apiKeyAuth, hmacAuth)).Post("/orders", ok)
+
+	// Each method alone is accepted
+	ta.POST("/orders").Header("X-API-KEY", "service-key").Do().
+		ExpectStatus(http.StatusOK).
+		ExpectBodyContains("service")
+	withHeaders(ta.POST("/orders").Body("application/json", "{}"), hmacHeaders(t, "POST", "/orders", "{}", "n1")).Do().
+		ExpectStatus(http.StatusOK).
+		ExpectBodyContains("partner")
+
+	t.Run("no credentials reports the first method", func(t *testing.T) {
+		ta.Logs.Reset()
+		ta.POST("/orders").Do().
+			ExpectStatus(http.StatusUnauthorized).
+			ExpectJSON(`{"code": "unauthorized", "message": "authentication required"}`)
+		expectAuthFailure(t, ta, "apikey", app.AuthFailureMissing)
+	})
+
+	t.Run("missing API key does not hide a bad signature", func(t *testing.T) {
+		ta.Logs.Reset()
+		header := hmacHeaders(t, "POST", "/orders", "{}", "n2")
+		header.Set("X-Signature", strings.Repeat("00", sha256.Size))
+		withHeaders(ta.POST("/orders").Body("application/json", "{}"), header).Do().
+			ExpectStatus(http.StatusUnauthorized)
+		expectAuthFailure(t, ta, "hmac", app.AuthFailureInvalidSignature)
+	})
+
+	t.Run("first method with credentials wins", func(t *testing.T) {
+		ta.Logs.Reset()
+		header := hmacHeaders(t, "POST", "/orders", "{}", "n3")
+		header.Set("X-Signature", strings.Repeat("00", sha256.Size))
+		withHeaders(ta.POST("/orders").Body("application/json", "{}"), header).
+			Header("X-API-KEY", "wrong-key").Do().
+			ExpectStatus(http.StatusUnauthorized)
+		expectAuthFailure(t, ta, "apikey", app.AuthFailureUnknown)
+	})
+}
+
+func newHMACApp(t *testing.T) *apptest.TestApp {
+	t.Helper()
+	mw, err := app.HMACMiddleware(app.HMACConfig{
+		Secrets: map[string]string{"partner": "s3cret"},
+		MaxSkew: time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ta := apptest.New(t)
+	ta.R.With(mw).Post("/webhooks/partner", ok)
+	return ta
+}
+
+func TestHMACSkew(t *testing.T) {
+	ta := newHMACApp(t)
+
+	for _, offset := range []time.Duration{-2 * time.Minute, 2 * time.Minute} {
+		t.Run(offset.String(), func(t *testing.T) {
+			header := hmacHeaders(t, "POST", "/webhooks/partner", "{}", "n-"+offset.String())
+			header.Set("X-Timestamp", strconv.FormatInt(time.Now().Add(offset).Unix(), 10))
+
+			withHeaders(ta.POST("/webhooks/partner").Body("application/json", "{}"), header).Do().
+				ExpectStatus(http.StatusUnauthorized).
+				ExpectErrorCode(app.CodeUnauthorized)
+			expectAuthFailure(t, ta, "hmac", app.AuthFailureStale)
+		})
+	}
+}
+
+func TestHMACReplay(t *testing.T) {
+	ta := newHMACApp(t)
+	header := hmacHeaders(t, "POST", "/webhooks/partner", `{"id":1}`, "once")
+
+	withHeaders(ta.POST("/webhooks/partner").Body("application/json", `{"id":1}`), header).Do().
+		ExpectStatus(http.StatusOK).
+		ExpectBodyContains("partner")
+
+	withHeaders(ta.POST("/webhooks/partner").Body("application/json", `{"id":1}`), header).Do().
+		ExpectStatus(http.StatusUnauthorized)
+	expectAuthFailure(t, ta, "hmac", app.AuthFailureReplayed)
+
+	// A tampered body is rejected before its nonce is remembered
+	tampered := hmacHeaders(t, "POST", "/webhooks/partner", `{"id":2}`, "tampered")
+	withHeaders(ta.POST("/webhooks/partner").Body("application/json", `{"id":3}`), tampered).Do().
+		ExpectStatus(http.StatusUnauthorized)
+	expectAuthFailure(t, ta, "hmac", app.AuthFailureInvalidSignature)
+}
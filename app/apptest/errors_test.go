@@ -0,0 +1,92 @@
+package apptest_test
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tendant/chi-demo/app"
+	"github.com/tendant/chi-demo/app/apptest"
+)
+
+func ok(w http.ResponseWriter, r *http.Request) {
+	principal, _ := app.PrincipalFromContext(r.Context())
+	w.Write([]byte(principal))
+}
+
+func TestErrorFormatNegotiation(t *testing.T) {
+	ta := apptest.New(t)
+	// chi only runs the middleware for unknown paths once a route exists
+	ta.R.Get("/orders", ok)
+
+	ta.GET("/missing").Do().
+		ExpectStatus(http.StatusNotFound).
+		ExpectHeader("Content-Type", "application/json").
+		ExpectErrorCode(app.CodeNotFound)
+
+	ta.GET("/missing").Header("Accept", "application/xml").Do().
+		ExpectStatus(http.StatusNotFound).
+		ExpectHeader("Content-Type", "application/json").
+		ExpectErrorCode(app.CodeNotFound)
+
+	ta.GET("/missing").Header("Accept", "application/problem+json;q=0").Do().
+		ExpectStatus(http.StatusNotFound).
+		ExpectHeader("Content-Type", "application/json")
+
+	var problem map[string]any
+	ta.GET("/missing").Header("Accept", app.ContentTypeProblemJSON).Header("X-Request-Id", "req-1").Do().
+		ExpectStatus(http.StatusNotFound).
+		ExpectHeader("Content-Type", app.ContentTypeProblemJSON).
+		DecodeJSON(&problem)
+	if problem["status"] != float64(http.StatusNotFound) || problem["code"] != app.CodeNotFound || problem["instance"] != "req-1" {
+		t.Errorf("problem = %v, want status 404, code not_found and instance req-1", problem)
+	}
+}
+
+func TestErrorFormatProblemDefault(t *testing.T) {
+	ta := apptest.New(t, app.WithErrorFormat(app.ErrorFormatProblem))
+	ta.R.Get("/orders", ok)
+
+	ta.GET("/missing").Do().
+		ExpectStatus(http.StatusNotFound).
+		ExpectHeader("Content-Type", app.ContentTypeProblemJSON).
+		ExpectBodyContains(`"title":"Not Found"`)
+
+	ta.GET("/missing").Header("Accept", "application/json").Do().
+		ExpectStatus(http.StatusNotFound).
+		ExpectHeader("Content-Type", "application/json").
+		ExpectErrorCode(app.CodeNotFound)
+}
+
+func TestRequestIDHeader(t *testing.T) {
+	ta := apptest.New(t)
+	ta.R.Get("/orders", ok)
+
+	ta.GET("/orders").Do().
+		ExpectStatus(http.StatusOK).
+		ExpectHeaderPresent("X-Request-Id")
+
+	ta.GET("/orders").Header("X-Request-Id", "req-2").Do().
+		ExpectHeader("X-Request-Id", "req-2")
+}
+
+func TestPanicRecovered(t *testing.T) {
+	ta := apptest.New(t)
+	ta.R.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	ta.GET("/panic").Header("X-Request-Id", "req-panic").Do().
+		ExpectStatus(http.StatusInternalServerError).
+		ExpectHeader("Content-Type", "application/json").
+		ExpectJSON(`{"code": "internal_error", "message": "Internal Server Error"}`)
+
+	rec := ta.Logs.Expect(t, slog.LevelError, "Panic recovered")
+	if rec.Attrs["panic"] != "boom" || rec.Attrs["route"] != "/panic" || rec.Attrs["request_id"] != "req-panic" {
+		t.Errorf("panic log attrs = %v", rec.Attrs)
+	}
+	if stack, _ := rec.Attrs["stack"].(string); !strings.Contains(stack, "TestPanicRecovered") {
+		t.Errorf("panic log stack = %q, want the handler's stack", stack)
+	}
+}
@@ -0,0 +1,143 @@
+package apptest
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// LogRecord is a captured log record with its attributes flattened into a
+// map; attributes in groups are keyed "group.key"
+type LogRecord struct {
+	Level   slog.Level
+	Message string
+	Attrs   map[string]any
+}
+
+// LogRecorder is a slog.Handler that records all log records in memory.
+// It is safe for concurrent use.
+type LogRecorder struct {
+	store  *logStore
+	attrs  []slog.Attr
+	groups []string
+}
+
+type logStore struct {
+	mu      sync.Mutex
+	records []LogRecord
+}
+
+// NewLogRecorder creates an empty recorder
+func NewLogRecorder() *LogRecorder {
+	return &LogRecorder{store: &logStore{}}
+}
+
+// Enabled implements slog.Handler; all levels are recorded
+func (l *LogRecorder) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle implements slog.Handler
+func (l *LogRecorder) Handle(_ context.Context, record slog.Record) error {
+	attrs := make(map[string]any)
+	for _, a := range l.attrs {
+		addAttr(attrs, "", a)
+	}
+	prefix := ""
+	if len(l.groups) > 0 {
+		prefix = strings.Join(l.groups, ".") + "."
+	}
+	record.Attrs(func(a slog.Attr) bool {
+		addAttr(attrs, prefix, a)
+		return true
+	})
+
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+	l.store.records = append(l.store.records, LogRecord{Level: record.Level, Message: record.Message, Attrs: attrs})
+	return nil
+}
+
+// WithAttrs implements slog.Handler
+func (l *LogRecorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *l
+	prefix := ""
+	if len(l.groups) > 0 {
+		prefix = strings.Join(l.groups, ".") + "."
+	}
+	c.attrs = slices.Clone(l.attrs)
+	for _, a := range attrs {
+		c.attrs = append(c.attrs, slog.Attr{Key: prefix + a.Key, Value: a.Value})
+	}
+	return &c
+}
+
+// WithGroup implements slog.Handler
+func (l *LogRecorder) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return l
+	}
+	c := *l
+	c.groups = append(slices.Clone(l.groups), name)
+	return &c
+}
+
+func addAttr(attrs map[string]any, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addAttr(attrs, prefix, ga)
+		}
+		return
+	}
+	attrs[prefix+a.Key] = v.Any()
+}
+
+// Records returns a copy of the recorded records
+func (l *LogRecorder) Records() []LogRecord {
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+	return slices.Clone(l.store.records)
+}
+
+// Find returns the first record with the given level and message
+func (l *LogRecorder) Find(level slog.Level, msg string) (LogRecord, bool) {
+	for _, r := range l.Records() {
+		if r.Level == level && r.Message == msg {
+			return r, true
+		}
+	}
+	return LogRecord{}, false
+}
+
+// Expect asserts that a record with the given level and message was logged
+// and returns it
+func (l *LogRecorder) Expect(t testing.TB, level slog.Level, msg string) LogRecord {
+	t.Helper()
+	r, ok := l.Find(level, msg)
+	if !ok {
+		t.Errorf("no %s log record %q; recorded: %v", level, msg, l.messages())
+	}
+	return r
+}
+
+// Reset discards all recorded records
+func (l *LogRecorder) Reset() {
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+	l.store.records = nil
+}
+
+func (l *LogRecorder) messages() []string {
+	var msgs []string
+	for _, r := range l.Records() {
+		msgs = append(msgs, r.Level.String()+" "+r.Message)
+	}
+	return msgs
+}
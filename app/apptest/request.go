@@ -0,0 +1,95 @@
+package apptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Request is a request to the test server, built fluently and sent with Do
+type Request struct {
+	t      testing.TB
+	ta     *TestApp
+	method string
+	path   string
+	header http.Header
+	body   io.Reader
+}
+
+// NewRequest starts building a request to path on the test server
+func (ta *TestApp) NewRequest(method, path string) *Request {
+	return &Request{t: ta.t, ta: ta, method: method, path: path, header: make(http.Header)}
+}
+
+// GET starts building a GET request
+func (ta *TestApp) GET(path string) *Request {
+	return ta.NewRequest(http.MethodGet, path)
+}
+
+// POST starts building a POST request
+func (ta *TestApp) POST(path string) *Request {
+	return ta.NewRequest(http.MethodPost, path)
+}
+
+// PUT starts building a PUT request
+func (ta *TestApp) PUT(path string) *Request {
+	return ta.NewRequest(http.MethodPut, path)
+}
+
+// DELETE starts building a DELETE request
+func (ta *TestApp) DELETE(path string) *Request {
+	return ta.NewRequest(http.MethodDelete, path)
+}
+
+// Header sets a request header
+func (r *Request) Header(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// Body sets the request body and its content type
+func (r *Request) Body(contentType, body string) *Request {
+	r.header.Set("Content-Type", contentType)
+	r.body = strings.NewReader(body)
+	return r
+}
+
+// JSON sets v, encoded as JSON, as the request body
+func (r *Request) JSON(v any) *Request {
+	r.t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		r.t.Fatalf("apptest: encode JSON body: %v", err)
+	}
+	r.header.Set("Content-Type", "application/json")
+	r.body = bytes.NewReader(data)
+	return r
+}
+
+// Do sends the request and reads the whole response
+func (r *Request) Do() *Response {
+	r.t.Helper()
+
+	req, err := http.NewRequest(r.method, r.ta.URL(r.path), r.body)
+	if err != nil {
+		r.t.Fatalf("apptest: create request %s %s: %v", r.method, r.path, err)
+	}
+	req.Header = r.header
+
+	resp, err := r.ta.Server.Client().Do(req)
+	if err != nil {
+		r.t.Fatalf("apptest: %s %s: %v", r.method, r.path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		r.t.Fatalf("apptest: read response of %s %s: %v", r.method, r.path, err)
+	}
+
+	return &Response{Response: resp, Body: body, t: r.t, desc: r.method + " " + r.path}
+}
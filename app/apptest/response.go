@@ -0,0 +1,108 @@
+package apptest
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tendant/chi-demo/app"
+)
+
+// Response is a received response with fluent assertions. Failed assertions
+// are reported with t.Errorf, so all of them are checked.
+type Response struct {
+	*http.Response
+	// Body is the complete response body
+	Body []byte
+
+	t    testing.TB
+	desc string
+}
+
+// ExpectStatus asserts the status code
+func (r *Response) ExpectStatus(status int) *Response {
+	r.t.Helper()
+	if r.StatusCode != status {
+		r.t.Errorf("%s: status = %d, want %d; body: %s", r.desc, r.StatusCode, status, r.Body)
+	}
+	return r
+}
+
+// ExpectHeader asserts the value of a response header
+func (r *Response) ExpectHeader(key, value string) *Response {
+	r.t.Helper()
+	if got := r.Header.Get(key); got != value {
+		r.t.Errorf("%s: header %s = %q, want %q", r.desc, key, got, value)
+	}
+	return r
+}
+
+// ExpectHeaderPresent asserts that a response header is set
+func (r *Response) ExpectHeaderPresent(key string) *Response {
+	r.t.Helper()
+	if r.Header.Get(key) == "" {
+		r.t.Errorf("%s: header %s is missing", r.desc, key)
+	}
+	return r
+}
+
+// ExpectVersion asserts the X-App-Version header set by the version middleware
+func (r *Response) ExpectVersion(version string) *Response {
+	r.t.Helper()
+	return r.ExpectHeader("X-App-Version", version)
+}
+
+// ExpectBodyContains asserts that the body contains s
+func (r *Response) ExpectBodyContains(s string) *Response {
+	r.t.Helper()
+	if !strings.Contains(string(r.Body), s) {
+		r.t.Errorf("%s: body does not contain %q; body: %s", r.desc, s, r.Body)
+	}
+	return r
+}
+
+// ExpectJSON asserts that the body is JSON equal to expected, ignoring
+// formatting and key order
+func (r *Response) ExpectJSON(expected string) *Response {
+	r.t.Helper()
+
+	var want, got any
+	if err := json.Unmarshal([]byte(expected), &want); err != nil {
+		r.t.Fatalf("apptest: invalid expected JSON: %v", err)
+	}
+	if err := json.Unmarshal(r.Body, &got); err != nil {
+		r.t.Errorf("%s: body is not JSON: %v; body: %s", r.desc, err, r.Body)
+		return r
+	}
+	if !reflect.DeepEqual(want, got) {
+		r.t.Errorf("%s: body = %s, want %s", r.desc, r.Body, expected)
+	}
+	return r
+}
+
+// DecodeJSON decodes the body into v
+func (r *Response) DecodeJSON(v any) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		r.t.Errorf("%s: decode JSON body: %v; body: %s", r.desc, err, r.Body)
+	}
+	return r
+}
+
+// ExpectErrorCode asserts that the body is a standard JSON error (see
+// README-error.md) with the given code
+func (r *Response) ExpectErrorCode(code string) *Response {
+	r.t.Helper()
+
+	var apiErr app.APIError
+	if err := json.Unmarshal(r.Body, &apiErr); err != nil {
+		r.t.Errorf("%s: body is not a JSON error: %v; body: %s", r.desc, err, r.Body)
+		return r
+	}
+	if apiErr.Code != code {
+		r.t.Errorf("%s: error code = %q, want %q; body: %s", r.desc, apiErr.Code, code, r.Body)
+	}
+	return r
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/httplog/v2"
+)
+
+// Authenticator authenticates requests using one method (API key, JWT, ...).
+// Implementations must be safe for concurrent use.
+type Authenticator interface {
+	// Name identifies the method, e.g. "apikey" or "jwt". It is used in logs,
+	// metrics and AuthMethodFromContext.
+	Name() string
+
+	// Authenticate returns a copy of the request context carrying the
+	// principal (see WithPrincipal) on success. On failure it returns an
+	// *AuthError; a Reason of AuthFailureMissing means the request carried
+	// no credentials for this method.
+	Authenticate(r *http.Request) (context.Context, error)
+}
+
+// AuthError describes why an Authenticator rejected a request
+type AuthError struct {
+	// Method is the Authenticator name
+	Method string
+	// Reason is one of the AuthFailure* constants
+	Reason string
+	// Principal is set when the credentials identified a principal that was
+	// nevertheless rejected (e.g. an expired key)
+	Principal string
+	// Err is the underlying error, if any
+	Err error
+}
+
+func (e *AuthError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s authentication failed: %s: %v", e.Method, e.Reason, e.Err)
+	}
+	return fmt.Sprintf("%s authentication failed: %s", e.Method, e.Reason)
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// challenger is implemented by authenticators that want to advertise
+// themselves in the WWW-Authenticate header of 401 responses
+type challenger interface {
+	Challenge() string
+}
+
+// authMethodCtxKey is the context key for the authentication method used
+type authMethodCtxKey struct{}
+
+// AuthMethodFromContext returns the name of the Authenticator that
+// authenticated the request, or "" if the request was not authenticated
+func AuthMethodFromContext(ctx context.Context) string {
+	method, _ := ctx.Value(authMethodCtxKey{}).(string)
+	return method
+}
+
+// Authenticate returns a middleware that rejects requests the authenticator
+// does not accept with 401 Unauthorized
+func Authenticate(a Authenticator) Middleware {
+	return AnyOf(a)
+}
+
+// AnyOf returns a middleware that tries each authenticator in order and
+// accepts the request as soon as one succeeds, e.g. to let machines use API
+// keys and browsers use JWTs on the same routes. The successful method is
+// available through AuthMethodFromContext. If all authenticators fail the
+// request is rejected with 401 Unauthorized and the same body regardless of
+// which methods were tried. It panics if no authenticators are given.
+//
+// Example:
+//
+//	r.Group(func(r chi.Router) {
+//	    r.Use(app.AnyOf(apiKeyAuth, jwtAuth))
+//	    r.Get("/orders", listOrders)
+//	})
+func AnyOf(authenticators ...Authenticator) Middleware {
+	if len(authenticators) == 0 {
+		panic("app: AnyOf requires at least one authenticator")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var failure *AuthError
+			for _, a := range authenticators {
+				ctx, err := a.Authenticate(r)
+				if err == nil {
+					httplog.LogEntrySetField(ctx, "auth_method", slog.StringValue(a.Name()))
+					ctx = context.WithValue(ctx, authMethodCtxKey{}, a.Name())
+					next.ServeHTTP(w, r.WithContext(ctx))
+					return
+				}
+
+				// Report the first method that found credentials, so a
+				// missing API key doesn't hide an expired JWT
+				var authErr *AuthError
+				if !errors.As(err, &authErr) {
+					authErr = &AuthError{Method: a.Name(), Reason: AuthFailureInvalidToken, Err: err}
+				}
+				if failure == nil || (failure.Reason == AuthFailureMissing && authErr.Reason != AuthFailureMissing) {
+					failure = authErr
+				}
+			}
+
+			authFailuresTotal.WithLabelValues(failure.Method, failure.Reason).Inc()
+			slog.Error("request failed authentication",
+				"method", failure.Method,
+				"reason", failure.Reason,
+				"principal", failure.Principal,
+				"err", failure.Err,
+				"remoteIP", remoteIP(r),
+			)
+
+			for _, a := range authenticators {
+				if c, ok := a.(challenger); ok {
+					w.Header().Add("WWW-Authenticate", c.Challenge())
+				}
+			}
+			RenderError(w, r, UnauthorizedError("authentication required").Wrap(failure))
+		})
+	}
+}
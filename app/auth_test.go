@@ -0,0 +1,12 @@
+package app
+
+import "testing"
+
+func TestAnyOfWithoutAuthenticatorsPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("AnyOf() did not panic")
+		}
+	}()
+	AnyOf()
+}
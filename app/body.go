@@ -0,0 +1,139 @@
+package app
+
+import (
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	httpin_core "github.com/ggicci/httpin/core"
+)
+
+// Error codes of request body errors
+const (
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeInvalidField         = "invalid_field"
+)
+
+// PayloadTooLargeError returns a 413 Payload Too Large error
+func PayloadTooLargeError(message string) *APIError {
+	return NewAPIError(http.StatusRequestEntityTooLarge, CodePayloadTooLarge, message)
+}
+
+// UnsupportedMediaTypeError returns a 415 Unsupported Media Type error
+func UnsupportedMediaTypeError(message string) *APIError {
+	return NewAPIError(http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, message)
+}
+
+// RequestBodyConfig configures RequestBody
+type RequestBodyConfig struct {
+	// MaxBytes caps the request body size; 0 means no limit
+	MaxBytes int64
+	// ContentTypes lists the accepted media types, e.g. "application/json"
+	// or "multipart/*". Empty means any. Requests without a body are
+	// not checked.
+	ContentTypes []string
+}
+
+// RequestBody returns a middleware that limits the request body size and the
+// accepted content types.
+//
+// Requests whose Content-Length exceeds MaxBytes are rejected with 413 Payload
+// Too Large before the handler runs. For chunked requests, reading past the
+// limit fails with *http.MaxBytesError, which RenderError (and the httpin
+// error handler installed by WithHttpin) turn into a 413 response as well.
+// Requests with a body of another content type get 415 Unsupported Media Type.
+//
+// Example:
+//
+//	r.Group(func(r chi.Router) {
+//	    r.Use(app.RequestBody(app.RequestBodyConfig{
+//	        MaxBytes:     1 << 20, // 1 MiB
+//	        ContentTypes: []string{"application/json"},
+//	    }))
+//	    r.Post("/orders", createOrder)
+//	})
+func RequestBody(cfg RequestBodyConfig) Middleware {
+	allowed := make([]string, 0, len(cfg.ContentTypes))
+	for _, ct := range cfg.ContentTypes {
+		allowed = append(allowed, strings.ToLower(strings.TrimSpace(ct)))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(allowed) > 0 && hasBody(r) {
+				mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+				if err != nil || !mediaTypeAllowed(mediaType, allowed) {
+					slog.Warn("Request rejected: unsupported content type",
+						"content_type", r.Header.Get("Content-Type"),
+						"path", r.URL.Path,
+					)
+					RenderError(w, r, UnsupportedMediaTypeError("unsupported content type").
+						WithErrors(APIError{Code: CodeUnsupportedMediaType, Message: "expected " + strings.Join(allowed, ", ")}))
+					return
+				}
+			}
+
+			if cfg.MaxBytes > 0 {
+				if r.ContentLength > cfg.MaxBytes {
+					slog.Warn("Request rejected: body too large",
+						"content_length", r.ContentLength,
+						"max_bytes", cfg.MaxBytes,
+						"path", r.URL.Path,
+					)
+					RenderError(w, r, payloadTooLarge(cfg.MaxBytes))
+					return
+				}
+				if r.Body != nil {
+					r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxBytes)
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// hasBody reports whether the request carries a body
+func hasBody(r *http.Request) bool {
+	return r.ContentLength > 0 || (r.ContentLength < 0 && r.Body != nil && r.Body != http.NoBody)
+}
+
+// mediaTypeAllowed matches mediaType against types such as "application/json" or "text/*"
+func mediaTypeAllowed(mediaType string, allowed []string) bool {
+	for _, a := range allowed {
+		if a == mediaType || (strings.HasSuffix(a, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(a, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+func payloadTooLarge(limit int64) *APIError {
+	return PayloadTooLargeError("request body too large").
+		WithErrors(APIError{Code: CodePayloadTooLarge, Message: "request body must not exceed " + strconv.FormatInt(limit, 10) + " bytes"})
+}
+
+// HttpinErrorHandler renders httpin decoding errors in the standard JSON error
+// format: bodies over the RequestBody limit become 413, invalid fields 422.
+// WithHttpin registers it as the httpin default error handler.
+func HttpinErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		RenderError(w, r, payloadTooLarge(maxBytesErr.Limit).Wrap(err))
+		return
+	}
+
+	var fieldErr *httpin_core.InvalidFieldError
+	if errors.As(err, &fieldErr) {
+		apiErr := NewAPIError(http.StatusUnprocessableEntity, CodeInvalidField, "invalid input").
+			WithErrors(APIError{Code: CodeInvalidField, Message: fieldErr.Error()})
+		RenderError(w, r, apiErr.Wrap(err))
+		return
+	}
+
+	RenderError(w, r, err)
+}
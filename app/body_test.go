@@ -0,0 +1,73 @@
+package app
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// echoBody echoes the request body, rendering read errors with RenderError
+var echoBody = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		RenderError(w, r, err)
+		return
+	}
+	w.Write(body)
+})
+
+func TestRequestBody(t *testing.T) {
+	h := RequestBody(RequestBodyConfig{
+		MaxBytes:     8,
+		ContentTypes: []string{"application/json", "multipart/*"},
+	})(echoBody)
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		chunked     bool
+		status      int
+		code        string
+	}{
+		{"within limit", "POST", "application/json", `{"a":1}`, false, http.StatusOK, ""},
+		{"content type parameters", "POST", "application/json; charset=utf-8", `{}`, false, http.StatusOK, ""},
+		{"wildcard content type", "POST", "multipart/form-data; boundary=x", `--x--`, false, http.StatusOK, ""},
+		{"no body is not checked", "GET", "", "", false, http.StatusOK, ""},
+		{"content length over limit", "POST", "application/json", `{"a":"123456"}`, false, http.StatusRequestEntityTooLarge, CodePayloadTooLarge},
+		{"chunked over limit", "POST", "application/json", `{"a":"123456"}`, true, http.StatusRequestEntityTooLarge, CodePayloadTooLarge},
+		{"unsupported content type", "POST", "text/plain", `hi`, false, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType},
+		{"missing content type", "POST", "", `{}`, false, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/orders", strings.NewReader(tt.body))
+			if tt.chunked {
+				r.ContentLength = -1
+			}
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.code == "" {
+				if w.Body.String() != tt.body {
+					t.Errorf("body = %q, want %q", w.Body, tt.body)
+				}
+				return
+			}
+			var body APIError
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Code != tt.code {
+				t.Errorf("body = %s, want JSON error with code %q", w.Body, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ConcurrencyLimitConfig configures ConcurrencyLimitMiddleware
+type ConcurrencyLimitConfig struct {
+	// MaxInFlight caps concurrent requests across the whole app (0 = unlimited)
+	MaxInFlight int
+	// Routes caps concurrent requests per chi route pattern, e.g. "/reports/{id}"
+	Routes map[string]int
+	// QueueTimeout is how long a request waits for a free slot before it is
+	// rejected. Zero rejects immediately when saturated.
+	QueueTimeout time.Duration
+	// RetryAfter is sent in the Retry-After header of rejected requests
+	// (default 1 second)
+	RetryAfter time.Duration
+}
+
+// ConcurrencyLimitMiddleware returns a middleware that limits the number of
+// requests processed at the same time, globally and per route. When all slots
+// are taken, requests wait up to QueueTimeout and are then rejected with 503
+// Service Unavailable and Retry-After, so the app sheds load instead of
+// falling over.
+//
+// In-flight requests are exported as the http_requests_in_flight gauge and
+// rejected requests as the http_requests_shed_total counter.
+func ConcurrencyLimitMiddleware(cfg ConcurrencyLimitConfig) Middleware {
+	var global chan struct{}
+	if cfg.MaxInFlight > 0 {
+		global = make(chan struct{}, cfg.MaxInFlight)
+	}
+
+	routes := make(map[string]chan struct{}, len(cfg.Routes))
+	for pattern, limit := range cfg.Routes {
+		if limit > 0 {
+			routes[pattern] = make(chan struct{}, limit)
+		}
+	}
+
+	retryAfter := cfg.RetryAfter
+	if retryAfter <= 0 {
+		retryAfter = time.Second
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routePattern(r)
+
+			var deadline <-chan time.Time
+			if cfg.QueueTimeout > 0 {
+				timer := time.NewTimer(cfg.QueueTimeout)
+				defer timer.Stop()
+				deadline = timer.C
+			}
+
+			// Take the route slot first: waiting for it while holding a
+			// global slot would let one saturated route starve all others
+			if sem, ok := routes[route]; ok {
+				if !acquireSlot(r, sem, deadline) {
+					shed(w, r, route, "route", retryAfter)
+					return
+				}
+				defer func() { <-sem }()
+			}
+
+			if global != nil {
+				if !acquireSlot(r, global, deadline) {
+					shed(w, r, route, "global", retryAfter)
+					return
+				}
+				defer func() { <-global }()
+			}
+
+			inFlight := requestsInFlight.WithLabelValues(route)
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// acquireSlot takes a slot from sem, waiting until deadline (if not nil) or
+// until the client goes away
+func acquireSlot(r *http.Request, sem chan struct{}, deadline <-chan time.Time) bool {
+	select {
+	case sem <- struct{}{}:
+		return true
+	default:
+	}
+
+	if deadline == nil {
+		return false
+	}
+
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-deadline:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// shed rejects the request with 503 Service Unavailable
+func shed(w http.ResponseWriter, r *http.Request, route, limit string, retryAfter time.Duration) {
+	requestsShedTotal.WithLabelValues(route, limit).Inc()
+	slog.Warn("Request shed: concurrency limit reached", "route", route, "limit", limit)
+
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+	writeError(w, r, ServiceUnavailableError("server is busy, retry later"))
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// blockingRouter serves /slow and /fast behind the concurrency limiter. /slow
+// signals entered and blocks until release is closed.
+func blockingRouter(cfg ConcurrencyLimitConfig) (r *chi.Mux, entered chan struct{}, release chan struct{}) {
+	entered = make(chan struct{}, 10)
+	release = make(chan struct{})
+
+	r = chi.NewRouter()
+	r.Use(ConcurrencyLimitMiddleware(cfg))
+	r.Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	})
+	r.Get("/fast", func(w http.ResponseWriter, r *http.Request) {})
+	return r, entered, release
+}
+
+// serveAsync serves a request in the background and returns its recorder
+// once done is closed
+func serveAsync(h http.Handler, path string) (w *httptest.ResponseRecorder, done chan struct{}) {
+	w = httptest.NewRecorder()
+	done = make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	}()
+	return w, done
+}
+
+func serve(h http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	return w
+}
+
+func TestConcurrencyLimitSheds(t *testing.T) {
+	r, entered, release := blockingRouter(ConcurrencyLimitConfig{MaxInFlight: 1})
+	_, done := serveAsync(r, "/slow")
+	<-entered
+
+	w := serve(r, "/fast")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("saturated: %d, Retry-After %q; want 503 and 1", w.Code, w.Header().Get("Retry-After"))
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	close(release)
+	<-done
+	if w := serve(r, "/fast"); w.Code != http.StatusOK {
+		t.Errorf("after release: %d, want 200", w.Code)
+	}
+}
+
+func TestConcurrencyLimitQueues(t *testing.T) {
+	r, entered, release := blockingRouter(ConcurrencyLimitConfig{MaxInFlight: 1, QueueTimeout: 5 * time.Second})
+	_, done := serveAsync(r, "/slow")
+	<-entered
+
+	queued, queuedDone := serveAsync(r, "/fast")
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	<-done
+	<-queuedDone
+	if queued.Code != http.StatusOK {
+		t.Errorf("queued request: %d, want 200", queued.Code)
+	}
+}
+
+func TestConcurrencyLimitQueueTimeout(t *testing.T) {
+	r, entered, release := blockingRouter(ConcurrencyLimitConfig{
+		MaxInFlight:  1,
+		QueueTimeout: 50 * time.Millisecond,
+		RetryAfter:   2500 * time.Millisecond,
+	})
+	defer close(release)
+	serveAsync(r, "/slow")
+	<-entered
+
+	start := time.Now()
+	w := serve(r, "/fast")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "3" {
+		t.Errorf("queue timeout: %d, Retry-After %q; want 503 and 3", w.Code, w.Header().Get("Retry-After"))
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Errorf("rejected after %s, want after the 50ms queue timeout", waited)
+	}
+}
+
+func TestConcurrencyLimitRouteDoesNotStarveOthers(t *testing.T) {
+	r, entered, release := blockingRouter(ConcurrencyLimitConfig{
+		MaxInFlight:  2,
+		Routes:       map[string]int{"/slow": 1},
+		QueueTimeout: 5 * time.Second,
+	})
+	_, done := serveAsync(r, "/slow")
+	<-entered
+	// Queued for the /slow route slot
+	_, queuedDone := serveAsync(r, "/slow")
+	time.Sleep(20 * time.Millisecond)
+
+	fast, fastDone := serveAsync(r, "/fast")
+	select {
+	case <-fastDone:
+		if fast.Code != http.StatusOK {
+			t.Errorf("/fast: %d, want 200", fast.Code)
+		}
+	case <-time.After(time.Second):
+		t.Error("/fast waited for a global slot held by a request queued on /slow")
+	}
+
+	close(release)
+	<-done
+	<-queuedDone
+	<-fastDone
+}
@@ -3,6 +3,8 @@ package app
 import (
 	"fmt"
 	"log/slog"
+	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -12,10 +14,77 @@ type AppConfig struct {
 	AppEnv  string `env:"APP_ENV" env-default:"dev"`
 	Host    string `env:"HOST" env-default:"localhost"`
 	Port    int    `env:"PORT" env-default:"3000"`
+	Server  ServerConfig
+	Listen  ListenConfig
+	TLS     TLSConfig
 	Metrics MetricsConfig
 
 	// UseHttpin enables httpin integration for request parsing
 	UseHttpin bool `env:"USE_HTTPIN" env-default:"false"`
+
+	// ErrorFormat selects the error response body: "json" (README-error.md)
+	// or "problem" (RFC 7807). Clients can always ask for problem details
+	// with "Accept: application/problem+json".
+	ErrorFormat string `env:"ERROR_FORMAT" env-default:"json"`
+}
+
+// ServerConfig represents http.Server limits and timeouts.
+// A zero duration means no timeout.
+type ServerConfig struct {
+	ReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT" env-default:"30s"`
+	ReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" env-default:"10s"`
+	WriteTimeout      time.Duration `env:"HTTP_WRITE_TIMEOUT" env-default:"60s"`
+	IdleTimeout       time.Duration `env:"HTTP_IDLE_TIMEOUT" env-default:"120s"`
+	MaxHeaderBytes    int           `env:"HTTP_MAX_HEADER_BYTES" env-default:"1048576"` // 1 MiB
+
+	// DrainPeriod is how long readiness fails before shutdown starts, so load
+	// balancers stop sending traffic while the server still serves it
+	DrainPeriod time.Duration `env:"SHUTDOWN_DRAIN_PERIOD" env-default:"0s"`
+	// ShutdownTimeout bounds the graceful shutdown after the drain period
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" env-default:"5s"`
+
+	// GracefulRestart makes SIGHUP start a new process of the same binary
+	// that takes over the listening sockets (see Server.Run)
+	GracefulRestart bool `env:"GRACEFUL_RESTART" env-default:"false"`
+	// RestartTimeout is how long to wait for the new process to become ready
+	RestartTimeout time.Duration `env:"GRACEFUL_RESTART_TIMEOUT" env-default:"30s"`
+}
+
+// ListenConfig selects where the main server listens instead of Host:Port
+type ListenConfig struct {
+	// UnixSocket is the path of a Unix domain socket to listen on
+	UnixSocket string `env:"UNIX_SOCKET"`
+	// UnixSocketMode is the file mode of the socket
+	UnixSocketMode os.FileMode `env:"UNIX_SOCKET_MODE" env-default:"0660"`
+	// UnixSocketCleanup removes a stale socket file at startup and the
+	// socket file on shutdown
+	UnixSocketCleanup bool `env:"UNIX_SOCKET_CLEANUP" env-default:"true"`
+	// SocketActivation uses listeners passed by systemd (LISTEN_FDS) when
+	// present. Listeners named "metrics" and "redirect" (FileDescriptorName=)
+	// are used for those servers, any other for the main server.
+	SocketActivation bool `env:"SOCKET_ACTIVATION" env-default:"false"`
+}
+
+// TLSConfig represents HTTPS configuration. TLS is enabled when a
+// certificate is configured; the files are reloaded when they change.
+type TLSConfig struct {
+	CertFile string `env:"TLS_CERT_FILE"`
+	KeyFile  string `env:"TLS_KEY_FILE"`
+	// MinVersion is "1.2" or "1.3"
+	MinVersion string `env:"TLS_MIN_VERSION" env-default:"1.2"`
+	// CipherSuites lists cipher suite names for TLS 1.2 and below, e.g.
+	// "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256". Empty selects Go's defaults.
+	CipherSuites []string `env:"TLS_CIPHER_SUITES" env-separator:","`
+	// ReloadInterval is how often the certificate files are checked for changes
+	ReloadInterval time.Duration `env:"TLS_RELOAD_INTERVAL" env-default:"5s"`
+	// ClientCAFile is a PEM bundle of CAs that sign client certificates (mTLS)
+	ClientCAFile string `env:"TLS_CLIENT_CA_FILE"`
+	// ClientAuth is "none", "request" or "require" (default "require" when
+	// ClientCAFile is set)
+	ClientAuth string `env:"TLS_CLIENT_AUTH"`
+	// RedirectPort starts a plain HTTP listener on this port that redirects
+	// to HTTPS (0 disables it)
+	RedirectPort int `env:"TLS_REDIRECT_PORT" env-default:"0"`
 }
 
 // MetricsConfig represents metrics server configuration
@@ -40,8 +109,31 @@ func DefaultAppConfig() AppConfig {
 
 // Validate validates the application configuration
 func (c *AppConfig) Validate() error {
-	if c.Port < 1 || c.Port > 65535 {
-		return fmt.Errorf("invalid port: %d (must be between 1 and 65535)", c.Port)
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("invalid port: %d (must be between 1 and 65535, or 0 for any free port)", c.Port)
+	}
+
+	if c.Server.ReadTimeout < 0 || c.Server.ReadHeaderTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 {
+		return fmt.Errorf("invalid server timeouts: must not be negative")
+	}
+	if c.Server.DrainPeriod < 0 || c.Server.ShutdownTimeout < 0 {
+		return fmt.Errorf("invalid shutdown config: drain period and timeout must not be negative")
+	}
+	if c.Server.MaxHeaderBytes < 0 {
+		return fmt.Errorf("invalid max header bytes: %d (must not be negative)", c.Server.MaxHeaderBytes)
+	}
+
+	if c.TLS.Enabled() {
+		if err := c.TLS.validate(); err != nil {
+			return err
+		}
+		if c.TLS.RedirectPort != 0 && c.TLS.RedirectPort == c.Port {
+			return fmt.Errorf("TLS redirect port and app port cannot be the same: %d", c.Port)
+		}
+	}
+
+	if c.ErrorFormat != "" && c.ErrorFormat != ErrorFormatJSON && c.ErrorFormat != ErrorFormatProblem {
+		return fmt.Errorf("invalid error format: %s (must be 'json' or 'problem')", c.ErrorFormat)
 	}
 
 	if c.Metrics.Enabled {
@@ -52,10 +144,10 @@ func (c *AppConfig) Validate() error {
 
 		// Only validate separate port if in separate mode
 		if c.Metrics.Mode == "separate" {
-			if c.Metrics.Port < 1 || c.Metrics.Port > 65535 {
-				return fmt.Errorf("invalid metrics port: %d (must be between 1 and 65535)", c.Metrics.Port)
+			if c.Metrics.Port < 0 || c.Metrics.Port > 65535 {
+				return fmt.Errorf("invalid metrics port: %d (must be between 1 and 65535, or 0 for any free port)", c.Metrics.Port)
 			}
-			if c.Metrics.Port == c.Port {
+			if c.Metrics.Port != 0 && c.Metrics.Port == c.Port {
 				return fmt.Errorf("metrics port cannot be the same as application port: %d", c.Port)
 			}
 		}
@@ -0,0 +1,242 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/render"
+)
+
+// Standard error codes used by the built-in middleware and error helpers
+const (
+	CodeBadRequest       = "bad_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeTooManyRequests  = "too_many_requests"
+	CodeInternal         = "internal_error"
+	CodeUnavailable      = "service_unavailable"
+)
+
+// APIError is the standard error response body described in README-error.md:
+//
+//	{
+//	    "message": "Human readable error message",
+//	    "code": "error code, defined by server",
+//	    "errors": [{"message": "...", "code": "..."}]
+//	}
+//
+// It implements render.Renderer, so it can be passed to render.Render directly,
+// but RenderError is usually more convenient.
+type APIError struct {
+	// Status is the HTTP status code of the response
+	Status int `json:"-"`
+	// Message is a human readable error message
+	Message string `json:"message"`
+	// Code is a machine readable error code
+	Code string `json:"code"`
+	// Errors holds nested errors, e.g. one per invalid field
+	Errors []APIError `json:"errors,omitempty"`
+
+	// Type is a URI identifying the problem type, used for problem+json
+	// responses (defaults to "about:blank")
+	Type string `json:"-"`
+	// Extensions are additional members for problem+json responses
+	Extensions map[string]any `json:"-"`
+
+	// Err is the underlying cause. It is logged but never sent to clients.
+	Err error `json:"-"`
+}
+
+// NewAPIError creates an API error with the given status, code and message
+func NewAPIError(status int, code, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+// BadRequestError returns a 400 Bad Request error
+func BadRequestError(message string) *APIError {
+	return NewAPIError(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+// UnauthorizedError returns a 401 Unauthorized error
+func UnauthorizedError(message string) *APIError {
+	return NewAPIError(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+// ForbiddenError returns a 403 Forbidden error
+func ForbiddenError(message string) *APIError {
+	return NewAPIError(http.StatusForbidden, CodeForbidden, message)
+}
+
+// NotFoundError returns a 404 Not Found error
+func NotFoundError(message string) *APIError {
+	return NewAPIError(http.StatusNotFound, CodeNotFound, message)
+}
+
+// TooManyRequestsError returns a 429 Too Many Requests error
+func TooManyRequestsError(message string) *APIError {
+	return NewAPIError(http.StatusTooManyRequests, CodeTooManyRequests, message)
+}
+
+// ServiceUnavailableError returns a 503 Service Unavailable error
+func ServiceUnavailableError(message string) *APIError {
+	return NewAPIError(http.StatusServiceUnavailable, CodeUnavailable, message)
+}
+
+// InternalError returns a 500 Internal Server Error wrapping err.
+// The cause is logged but not exposed to clients.
+func InternalError(err error) *APIError {
+	return &APIError{
+		Status:  http.StatusInternalServerError,
+		Code:    CodeInternal,
+		Message: http.StatusText(http.StatusInternalServerError),
+		Err:     err,
+	}
+}
+
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// WithErrors returns a copy of e with the given nested errors
+func (e *APIError) WithErrors(errs ...APIError) *APIError {
+	c := *e
+	c.Errors = append(append([]APIError(nil), e.Errors...), errs...)
+	return &c
+}
+
+// Wrap returns a copy of e with the given underlying cause
+func (e *APIError) Wrap(err error) *APIError {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// Render implements render.Renderer
+func (e *APIError) Render(w http.ResponseWriter, r *http.Request) error {
+	if e.Status == 0 {
+		e.Status = http.StatusInternalServerError
+	}
+	render.Status(r, e.Status)
+	return nil
+}
+
+// StatusCoder can be implemented by custom errors to choose the HTTP status
+// code RenderError responds with
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// ToAPIError maps err to an APIError:
+//   - *APIError is returned as is
+//   - *AuthError becomes 401 Unauthorized
+//   - context.DeadlineExceeded becomes 504 Gateway Timeout
+//   - *http.MaxBytesError (see RequestBody) becomes 413 Payload Too Large
+//   - errors implementing StatusCoder use their status code and error text
+//   - everything else becomes 500 Internal Server Error
+func ToAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return UnauthorizedError(http.StatusText(http.StatusUnauthorized)).Wrap(err)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return GatewayTimeoutError("request timed out").Wrap(err)
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return payloadTooLarge(maxBytesErr.Limit).Wrap(err)
+	}
+
+	var sc StatusCoder
+	if errors.As(err, &sc) {
+		status := sc.StatusCode()
+		if status == http.StatusServiceUnavailable {
+			return ServiceUnavailableError(http.StatusText(status)).Wrap(err)
+		}
+		if status >= 500 {
+			return NewAPIError(status, CodeInternal, http.StatusText(status)).Wrap(err)
+		}
+		return NewAPIError(status, codeForStatus(status), err.Error()).Wrap(err)
+	}
+
+	return InternalError(err)
+}
+
+// RenderError writes err as a JSON error response, either in the
+// README-error.md format or as RFC 7807 problem details (see ErrorFormat).
+// Server errors (5xx) are logged together with their cause.
+func RenderError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := ToAPIError(err)
+	if apiErr.Status >= 500 {
+		slog.Error("Request failed",
+			"status", apiErr.Status,
+			"code", apiErr.Code,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"err", apiErr.Err,
+		)
+	}
+
+	writeError(w, r, apiErr)
+}
+
+// writeError renders the error in the format selected for the request
+func writeError(w http.ResponseWriter, r *http.Request, apiErr *APIError) {
+	if errorFormat(r) == ErrorFormatProblem {
+		renderProblem(w, r, apiErr)
+		return
+	}
+
+	// Always JSON: render.Render would pick the encoder from the Accept
+	// header and fail for clients asking for anything else
+	apiErr.Render(w, r)
+	render.JSON(w, r, apiErr)
+}
+
+// NotFoundHandler responds with a 404 JSON error
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	RenderError(w, r, NotFoundError(http.StatusText(http.StatusNotFound)))
+}
+
+// MethodNotAllowedHandler responds with a 405 JSON error
+func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	RenderError(w, r, NewAPIError(http.StatusMethodNotAllowed, CodeMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed)))
+}
+
+// codeForStatus returns the standard error code for a 4xx status
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	default:
+		return strings.ToLower(sanitizeLabel(http.StatusText(status)))
+	}
+}
@@ -0,0 +1,36 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRenderErrorIgnoresAccept(t *testing.T) {
+	for _, accept := range []string{"", "application/json", "application/xml", "text/html"} {
+		t.Run(accept, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if accept != "" {
+				r.Header.Set("Accept", accept)
+			}
+			w := httptest.NewRecorder()
+
+			RenderError(w, r, NewAPIError(http.StatusNotFound, "not_found", "no such order"))
+
+			if w.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			var body APIError
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %q: %v", w.Body, err)
+			}
+			if body.Code != "not_found" {
+				t.Errorf("code = %q, want not_found", body.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultFileWatchInterval is the default polling interval for file watchers
+const DefaultFileWatchInterval = 5 * time.Second
+
+// fileWatcher polls a set of files and calls onChange when any of them
+// changes (modification time or size). Polling is used instead of inotify so
+// that it works the same everywhere, including with Kubernetes secret volumes
+// which are updated through symlink swaps.
+type fileWatcher struct {
+	paths    []string
+	interval time.Duration
+	onChange func()
+
+	states   map[string]fileState
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// watchFiles starts polling the given paths and returns the watcher.
+// Call stopWatching to stop the background goroutine.
+func watchFiles(interval time.Duration, onChange func(), paths ...string) *fileWatcher {
+	if interval <= 0 {
+		interval = DefaultFileWatchInterval
+	}
+
+	w := &fileWatcher{
+		paths:    paths,
+		interval: interval,
+		onChange: onChange,
+		states:   make(map[string]fileState, len(paths)),
+		stop:     make(chan struct{}),
+	}
+	w.changed() // record initial state
+
+	go w.loop()
+	return w
+}
+
+func (w *fileWatcher) loop() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			if w.changed() {
+				w.onChange()
+			}
+		}
+	}
+}
+
+// changed stats all files and reports whether any of them differ from the
+// previously recorded state
+func (w *fileWatcher) changed() bool {
+	changed := false
+	for _, path := range w.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			slog.Warn("Failed to stat watched file", "path", path, "err", err)
+			continue
+		}
+
+		state := fileState{modTime: info.ModTime(), size: info.Size()}
+		if prev, ok := w.states[path]; ok && prev != state {
+			changed = true
+		}
+		w.states[path] = state
+	}
+	return changed
+}
+
+// stopWatching stops the watcher. It is safe to call more than once.
+func (w *fileWatcher) stopWatching() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+}
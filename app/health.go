@@ -0,0 +1,189 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+// DefaultHealthCheckTimeout is the timeout of checks that don't set one
+const DefaultHealthCheckTimeout = 2 * time.Second
+
+// Health statuses reported by /healthz/ready
+const (
+	HealthStatusOK           = "ok"
+	HealthStatusDegraded     = "degraded" // a non-critical check failed
+	HealthStatusFail         = "fail"
+	HealthStatusShuttingDown = "shutting_down"
+)
+
+// HealthCheckFunc checks a dependency. It should respect ctx cancellation.
+type HealthCheckFunc func(ctx context.Context) error
+
+// HealthCheck is a named readiness check
+type HealthCheck struct {
+	Name  string
+	Check HealthCheckFunc
+	// Timeout bounds the check (default DefaultHealthCheckTimeout)
+	Timeout time.Duration
+	// Critical checks make the app not ready when they fail; non-critical
+	// failures are reported as degraded but keep the app ready
+	Critical bool
+}
+
+// HealthCheckResult is the outcome of one check
+type HealthCheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthReport is the readiness response body
+type HealthReport struct {
+	Status string              `json:"status"`
+	Checks []HealthCheckResult `json:"checks"`
+}
+
+// HealthRegistry holds the readiness checks of an app and tracks whether it
+// is shutting down. It is safe for concurrent use.
+type HealthRegistry struct {
+	mu           sync.RWMutex
+	checks       []HealthCheck
+	shuttingDown atomic.Bool
+}
+
+// NewHealthRegistry creates an empty registry
+func NewHealthRegistry() *HealthRegistry {
+	return &HealthRegistry{}
+}
+
+// Register adds a check, replacing any check with the same name
+func (h *HealthRegistry) Register(check HealthCheck) {
+	if check.Timeout <= 0 {
+		check.Timeout = DefaultHealthCheckTimeout
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, c := range h.checks {
+		if c.Name == check.Name {
+			h.checks[i] = check
+			return
+		}
+	}
+	h.checks = append(h.checks, check)
+}
+
+// SetShuttingDown makes readiness fail from now on
+func (h *HealthRegistry) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// ShuttingDown reports whether graceful shutdown has begun
+func (h *HealthRegistry) ShuttingDown() bool {
+	return h.shuttingDown.Load()
+}
+
+// Check runs all checks in parallel and returns the aggregated report
+func (h *HealthRegistry) Check(ctx context.Context) HealthReport {
+	h.mu.RLock()
+	checks := append([]HealthCheck(nil), h.checks...)
+	h.mu.RUnlock()
+
+	results := make([]HealthCheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runHealthCheck(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	status := HealthStatusOK
+	for _, result := range results {
+		if result.Status == HealthStatusOK {
+			continue
+		}
+		if result.Critical {
+			status = HealthStatusFail
+		} else if status == HealthStatusOK {
+			status = HealthStatusDegraded
+		}
+	}
+
+	return HealthReport{Status: status, Checks: results}
+}
+
+// runHealthCheck runs one check with its timeout. A check that ignores ctx is
+// abandoned when the timeout expires.
+func runHealthCheck(ctx context.Context, check HealthCheck) HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rvr := recover(); rvr != nil {
+				done <- fmt.Errorf("panic: %v", rvr)
+			}
+		}()
+		done <- check.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", check.Timeout)
+	}
+
+	result := HealthCheckResult{
+		Name:      check.Name,
+		Status:    HealthStatusOK,
+		Critical:  check.Critical,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = HealthStatusFail
+		result.Error = err.Error()
+		slog.Warn("Health check failed", "check", check.Name, "critical", check.Critical, "err", err)
+	}
+	return result
+}
+
+// LivenessHandler responds 200 OK as long as the process can serve requests
+func (h *HealthRegistry) LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.PlainText(w, r, http.StatusText(http.StatusOK))
+	}
+}
+
+// ReadinessHandler runs the checks and responds with the JSON report:
+// 200 if all critical checks pass, 503 if one fails or shutdown has begun
+func (h *HealthRegistry) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.ShuttingDown() {
+			render.Status(r, http.StatusServiceUnavailable)
+			render.JSON(w, r, HealthReport{Status: HealthStatusShuttingDown, Checks: []HealthCheckResult{}})
+			return
+		}
+
+		report := h.Check(r.Context())
+		if report.Status == HealthStatusFail {
+			render.Status(r, http.StatusServiceUnavailable)
+		}
+		render.JSON(w, r, report)
+	}
+}
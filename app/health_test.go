@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func readiness(t *testing.T, h http.Handler) (int, HealthReport) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/healthz/ready", nil))
+
+	var report HealthReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("readiness body %q: %v", w.Body, err)
+	}
+	return w.Code, report
+}
+
+func TestHealthRegistryReadiness(t *testing.T) {
+	failing := func(ctx context.Context) error { return errors.New("down") }
+	passing := func(ctx context.Context) error { return nil }
+
+	tests := []struct {
+		name       string
+		checks     []HealthCheck
+		wantCode   int
+		wantStatus string
+	}{
+		{"no checks", nil, http.StatusOK, HealthStatusOK},
+		{"all pass", []HealthCheck{{Name: "db", Check: passing, Critical: true}}, http.StatusOK, HealthStatusOK},
+		{"non-critical fails", []HealthCheck{
+			{Name: "db", Check: passing, Critical: true},
+			{Name: "cache", Check: failing},
+		}, http.StatusOK, HealthStatusDegraded},
+		{"critical fails", []HealthCheck{
+			{Name: "db", Check: failing, Critical: true},
+			{Name: "cache", Check: failing},
+		}, http.StatusServiceUnavailable, HealthStatusFail},
+		{"panic", []HealthCheck{
+			{Name: "db", Check: func(ctx context.Context) error { panic("boom") }, Critical: true},
+		}, http.StatusServiceUnavailable, HealthStatusFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthRegistry()
+			for _, check := range tt.checks {
+				h.Register(check)
+			}
+			code, report := readiness(t, h.ReadinessHandler())
+			if code != tt.wantCode || report.Status != tt.wantStatus || len(report.Checks) != len(tt.checks) {
+				t.Errorf("readiness = %d %+v, want %d %s", code, report, tt.wantCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHealthRegistryRunsChecksInParallelWithTimeouts(t *testing.T) {
+	h := NewHealthRegistry()
+	slow := func(ctx context.Context) error {
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	}
+	h.Register(HealthCheck{Name: "a", Check: slow})
+	h.Register(HealthCheck{Name: "b", Check: slow})
+	// Ignores ctx, so it is abandoned at its timeout
+	h.Register(HealthCheck{Name: "stuck", Check: func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}, Timeout: 50 * time.Millisecond, Critical: true})
+
+	start := time.Now()
+	report := h.Check(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("checks took %s, want them run in parallel", elapsed)
+	}
+	if report.Status != HealthStatusFail {
+		t.Errorf("status = %s, want %s", report.Status, HealthStatusFail)
+	}
+	for _, result := range report.Checks {
+		if result.Name == "stuck" && result.Status != HealthStatusFail {
+			t.Errorf("stuck check = %+v, want timed out", result)
+		}
+		if result.Name != "stuck" && (result.Status != HealthStatusOK || result.LatencyMs < 100) {
+			t.Errorf("check %+v, want ok with its latency", result)
+		}
+	}
+}
+
+func TestHealthRegistryRegisterReplaces(t *testing.T) {
+	h := NewHealthRegistry()
+	h.Register(HealthCheck{Name: "db", Check: func(ctx context.Context) error { return errors.New("down") }, Critical: true})
+	h.Register(HealthCheck{Name: "db", Check: func(ctx context.Context) error { return nil }, Critical: true})
+
+	if report := h.Check(context.Background()); report.Status != HealthStatusOK || len(report.Checks) != 1 {
+		t.Errorf("report = %+v, want the replacement check only", report)
+	}
+}
+
+func TestAppHealthzRoutesFailReadinessOnShutdown(t *testing.T) {
+	a := NewApp()
+	a.RegisterHealthzRoutes()
+
+	if code, _ := readiness(t, a.R); code != http.StatusOK {
+		t.Fatalf("readiness = %d, want 200", code)
+	}
+
+	a.Health.SetShuttingDown()
+	code, report := readiness(t, a.R)
+	if code != http.StatusServiceUnavailable || report.Status != HealthStatusShuttingDown {
+		t.Errorf("readiness during shutdown = %d %+v, want 503 %s", code, report, HealthStatusShuttingDown)
+	}
+
+	// Liveness is unaffected
+	w := httptest.NewRecorder()
+	a.R.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("liveness during shutdown = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,263 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HMACConfig configures HMAC request signature verification.
+//
+// Clients sign the string
+//
+//	METHOD + "\n" + REQUEST_URI + "\n" + TIMESTAMP + "\n" + NONCE + "\n" + BODY
+//
+// with HMAC-SHA256 using the shared secret of their key, and send the
+// hex-encoded signature together with the key name, timestamp and nonce in
+// headers. SignRequest does this for Go clients.
+type HMACConfig struct {
+	// Secrets maps key names to shared secrets
+	Secrets map[string]string
+
+	// KeyIDHeader carries the key name (default "X-Key-Id")
+	KeyIDHeader string
+	// SignatureHeader carries the hex-encoded signature (default "X-Signature")
+	SignatureHeader string
+	// TimestampHeader carries the Unix time in seconds (default "X-Timestamp")
+	TimestampHeader string
+	// NonceHeader carries a unique value per request (default "X-Nonce")
+	NonceHeader string
+
+	// MaxSkew is the maximum accepted age of (or clock skew in) the timestamp.
+	// Defaults to 5 minutes.
+	MaxSkew time.Duration
+	// MaxBodyBytes limits the body size read for verification (default 1 MiB)
+	MaxBodyBytes int64
+}
+
+// HMAC authentication failure reasons, in addition to AuthFailureMissing,
+// AuthFailureUnknown and AuthFailureTooLong
+const (
+	AuthFailureInvalidSignature = "invalid_signature"
+	AuthFailureStale            = "stale"
+	AuthFailureReplayed         = "replayed"
+	AuthFailureUnreadableBody   = "unreadable_body"
+)
+
+const (
+	defaultHMACKeyIDHeader     = "X-Key-Id"
+	defaultHMACSignatureHeader = "X-Signature"
+	defaultHMACTimestampHeader = "X-Timestamp"
+	defaultHMACNonceHeader     = "X-Nonce"
+	defaultHMACMaxSkew         = 5 * time.Minute
+	defaultHMACMaxBodyBytes    = 1 << 20
+)
+
+// HMACAuthenticator verifies HMAC-signed requests.
+// Create it with NewHMACAuthenticator.
+type HMACAuthenticator struct {
+	cfg    HMACConfig
+	nonces *nonceCache
+}
+
+// NewHMACAuthenticator creates an Authenticator from the HMAC configuration
+func NewHMACAuthenticator(cfg HMACConfig) (*HMACAuthenticator, error) {
+	if len(cfg.Secrets) == 0 {
+		return nil, errors.New("hmac: no secrets configured")
+	}
+	if cfg.KeyIDHeader == "" {
+		cfg.KeyIDHeader = defaultHMACKeyIDHeader
+	}
+	if cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = defaultHMACSignatureHeader
+	}
+	if cfg.TimestampHeader == "" {
+		cfg.TimestampHeader = defaultHMACTimestampHeader
+	}
+	if cfg.NonceHeader == "" {
+		cfg.NonceHeader = defaultHMACNonceHeader
+	}
+	if cfg.MaxSkew <= 0 {
+		cfg.MaxSkew = defaultHMACMaxSkew
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = defaultHMACMaxBodyBytes
+	}
+
+	return &HMACAuthenticator{
+		cfg: cfg,
+		// A nonce only needs to be remembered while its timestamp is acceptable
+		nonces: newNonceCache(2 * cfg.MaxSkew),
+	}, nil
+}
+
+// Name implements Authenticator
+func (a *HMACAuthenticator) Name() string {
+	return "hmac"
+}
+
+// Authenticate implements Authenticator. The request body is read for
+// verification and replaced so downstream handlers can read it again.
+func (a *HMACAuthenticator) Authenticate(r *http.Request) (context.Context, error) {
+	keyID := r.Header.Get(a.cfg.KeyIDHeader)
+	signature := r.Header.Get(a.cfg.SignatureHeader)
+	timestamp := r.Header.Get(a.cfg.TimestampHeader)
+	nonce := r.Header.Get(a.cfg.NonceHeader)
+	if keyID == "" || signature == "" || timestamp == "" || nonce == "" {
+		return nil, &AuthError{Method: a.Name(), Reason: AuthFailureMissing}
+	}
+
+	secret, ok := a.cfg.Secrets[keyID]
+	if !ok {
+		return nil, &AuthError{Method: a.Name(), Reason: AuthFailureUnknown,
+			Err: fmt.Errorf("unknown key id %q", keyID)}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, &AuthError{Method: a.Name(), Reason: AuthFailureStale, Principal: keyID,
+			Err: fmt.Errorf("invalid timestamp: %w", err)}
+	}
+	if skew := time.Since(time.Unix(unix, 0)); skew > a.cfg.MaxSkew || skew < -a.cfg.MaxSkew {
+		return nil, &AuthError{Method: a.Name(), Reason: AuthFailureStale, Principal: keyID,
+			Err: fmt.Errorf("timestamp skew %s exceeds %s", skew.Round(time.Second), a.cfg.MaxSkew)}
+	}
+
+	body, err := readBody(r, a.cfg.MaxBodyBytes)
+	if err != nil {
+		reason := AuthFailureUnreadableBody
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			reason = AuthFailureTooLong
+		}
+		return nil, &AuthError{Method: a.Name(), Reason: reason, Principal: keyID, Err: err}
+	}
+
+	expected := hmacSignature(secret, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+	given, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, given) {
+		return nil, &AuthError{Method: a.Name(), Reason: AuthFailureInvalidSignature, Principal: keyID}
+	}
+
+	// Only remember nonces of correctly signed requests, so unauthenticated
+	// clients cannot fill the cache
+	if !a.nonces.add(keyID+":"+nonce, time.Now()) {
+		return nil, &AuthError{Method: a.Name(), Reason: AuthFailureReplayed, Principal: keyID}
+	}
+
+	return setPrincipal(r.Context(), keyID), nil
+}
+
+// HMACMiddleware returns a middleware that only lets through requests with a
+// valid HMAC signature
+func HMACMiddleware(cfg HMACConfig) (Middleware, error) {
+	a, err := NewHMACAuthenticator(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return Authenticate(a), nil
+}
+
+// SignRequest signs r for verification by HMACAuthenticator using the default
+// header names. The body is read and replaced.
+func SignRequest(r *http.Request, keyID, secret, nonce string) error {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := hmacSignature(secret, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+
+	r.Header.Set(defaultHMACKeyIDHeader, keyID)
+	r.Header.Set(defaultHMACTimestampHeader, timestamp)
+	r.Header.Set(defaultHMACNonceHeader, nonce)
+	r.Header.Set(defaultHMACSignatureHeader, hex.EncodeToString(signature))
+	return nil
+}
+
+// hmacSignature computes the HMAC-SHA256 of the canonical request string
+func hmacSignature(secret, method, requestURI, timestamp, nonce string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	io.WriteString(mac, strings.ToUpper(method)+"\n"+requestURI+"\n"+timestamp+"\n"+nonce+"\n")
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// readBody reads up to limit bytes of the request body and replaces it with
+// an in-memory copy. It fails with a *http.MaxBytesError if the body is
+// larger than limit; the body then still yields its full content, so a later
+// Authenticator or handler can read it.
+func readBody(r *http.Request, limit int64) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		return nil, &http.MaxBytesError{Limit: limit}
+	}
+
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// nonceCache remembers recently seen nonces to reject replayed requests
+type nonceCache struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+func newNonceCache(ttl time.Duration) *nonceCache {
+	return &nonceCache{
+		ttl:  ttl,
+		seen: make(map[string]time.Time),
+	}
+}
+
+// add records the nonce and reports whether it was not seen before
+func (c *nonceCache) add(nonce string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastSweep) > c.ttl {
+		for n, seenAt := range c.seen {
+			if now.Sub(seenAt) > c.ttl {
+				delete(c.seen, n)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	if seenAt, ok := c.seen[nonce]; ok && now.Sub(seenAt) <= c.ttl {
+		return false
+	}
+	c.seen[nonce] = now
+	return true
+}
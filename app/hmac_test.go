@@ -0,0 +1,56 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestHMACAuthenticatorOversizeBody(t *testing.T) {
+	a, err := NewHMACAuthenticator(HMACConfig{Secrets: map[string]string{"svc": "secret"}, MaxBodyBytes: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("POST", "/orders", strings.NewReader("0123456789"))
+	if err := SignRequest(r, "svc", "secret", "n1"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = a.Authenticate(r)
+	var authErr *AuthError
+	if !errors.As(err, &authErr) || authErr.Reason != AuthFailureTooLong {
+		t.Fatalf("err = %v, want reason %q", err, AuthFailureTooLong)
+	}
+
+	// A later authenticator or handler must still see the full body
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "0123456789" {
+		t.Errorf("body = %q, want full body", body)
+	}
+}
+
+func TestHMACAuthenticatorUnreadableBody(t *testing.T) {
+	a, err := NewHMACAuthenticator(HMACConfig{Secrets: map[string]string{"svc": "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("POST", "/orders", strings.NewReader("{}"))
+	if err := SignRequest(r, "svc", "secret", "n1"); err != nil {
+		t.Fatal(err)
+	}
+	r.Body = io.NopCloser(iotest.ErrReader(errors.New("connection reset")))
+
+	_, err = a.Authenticate(r)
+	var authErr *AuthError
+	if !errors.As(err, &authErr) || authErr.Reason != AuthFailureUnreadableBody {
+		t.Fatalf("err = %v, want reason %q", err, AuthFailureUnreadableBody)
+	}
+}
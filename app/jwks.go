@@ -0,0 +1,190 @@
+package app
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// JWK is a single JSON Web Key (RFC 7517). Only the fields needed to verify
+// RSA, ECDSA and HMAC signatures are supported.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+
+	// Symmetric
+	K string `json:"k,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// verificationKey is a decoded key usable for signature verification
+type verificationKey struct {
+	kid string
+	alg string
+	// key is *rsa.PublicKey, *ecdsa.PublicKey or []byte
+	key any
+}
+
+// loadJWKSFile reads and decodes a JWKS file
+func loadJWKSFile(path string) ([]verificationKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jwks file: %w", err)
+	}
+
+	var set JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse jwks file %s: %w", path, err)
+	}
+
+	keys := make([]verificationKey, 0, len(set.Keys))
+	for i, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.decode()
+		if err != nil {
+			return nil, fmt.Errorf("parse jwks file %s: key %d (kid %q): %w", path, i, jwk.Kid, err)
+		}
+		keys = append(keys, verificationKey{kid: jwk.Kid, alg: jwk.Alg, key: key})
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("parse jwks file %s: no signing keys found", path)
+	}
+	return keys, nil
+}
+
+// loadPEMFile reads an RSA or ECDSA public key, or a certificate, from a PEM file
+func loadPEMFile(path string) ([]verificationKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read pem file: %w", err)
+	}
+
+	var keys []verificationKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		var key any
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			cert, err = x509.ParseCertificate(block.Bytes)
+			if err == nil {
+				key = cert.PublicKey
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse pem file %s: %w", path, err)
+		}
+
+		switch key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey:
+			keys = append(keys, verificationKey{key: key})
+		default:
+			return nil, fmt.Errorf("parse pem file %s: unsupported key type %T", path, key)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("parse pem file %s: no public keys found", path)
+	}
+	return keys, nil
+}
+
+// decode converts the JWK into a Go crypto key
+func (k JWK) decode() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeB64Int(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := decodeB64Int(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeB64Int(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate: %w", err)
+		}
+		y, err := decodeB64Int(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(k.K, "="))
+		if err != nil {
+			return nil, fmt.Errorf("invalid symmetric key: %w", err)
+		}
+		return secret, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeB64Int(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
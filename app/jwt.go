@@ -0,0 +1,273 @@
+package app
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTConfig configures JWTMiddleware.
+// At least one of JWKSFile, PEMFile or HMACSecret must be set.
+type JWTConfig struct {
+	// JWKSFile is the path to a local JSON Web Key Set file
+	JWKSFile string
+	// PEMFile is the path to a PEM file with RSA/ECDSA public keys or certificates
+	PEMFile string
+	// HMACSecret is the shared secret for HS256 tokens
+	HMACSecret []byte
+
+	// Algorithms lists the accepted signing algorithms.
+	// Defaults to RS256, ES256 and HS256.
+	Algorithms []string
+	// Issuer is the required "iss" claim (not checked if empty)
+	Issuer string
+	// Audience is the required "aud" claim (not checked if empty)
+	Audience string
+	// Leeway allows for clock skew when checking "exp" and "nbf"
+	Leeway time.Duration
+
+	// Extractors are tried in order to find the token.
+	// Defaults to the "Authorization: Bearer" header.
+	Extractors []TokenExtractor
+	// PrincipalClaim is the claim used as the request principal (default "sub")
+	PrincipalClaim string
+	// ScopeClaim is the claim holding granted scopes, either a space separated
+	// string or a list (default "scope")
+	ScopeClaim string
+}
+
+// DefaultJWTAlgorithms are the signing algorithms accepted when JWTConfig.Algorithms is empty
+var DefaultJWTAlgorithms = []string{"RS256", "ES256", "HS256"}
+
+// JWT authentication failure reasons, in addition to AuthFailureMissing,
+// AuthFailureExpired and AuthFailureNotYetValid
+const (
+	AuthFailureInvalidToken    = "invalid_token"
+	AuthFailureInvalidIssuer   = "invalid_issuer"
+	AuthFailureInvalidAudience = "invalid_audience"
+)
+
+// claimsCtxKey is the context key for verified JWT claims
+type claimsCtxKey struct{}
+
+// ClaimsFromContext returns the verified JWT claims stored by JWTMiddleware
+func ClaimsFromContext(ctx context.Context) (jwt.MapClaims, bool) {
+	claims, ok := ctx.Value(claimsCtxKey{}).(jwt.MapClaims)
+	return claims, ok
+}
+
+// JWTAuthenticator authenticates requests with JWT bearer tokens.
+// Create it with NewJWTAuthenticator.
+type JWTAuthenticator struct {
+	parser         *jwt.Parser
+	keys           []verificationKey
+	extractors     []TokenExtractor
+	principalClaim string
+	scopeClaim     string
+}
+
+// NewJWTAuthenticator creates an Authenticator from the JWT configuration
+func NewJWTAuthenticator(cfg JWTConfig) (*JWTAuthenticator, error) {
+	var keys []verificationKey
+	if cfg.JWKSFile != "" {
+		jwksKeys, err := loadJWKSFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, jwksKeys...)
+	}
+	if cfg.PEMFile != "" {
+		pemKeys, err := loadPEMFile(cfg.PEMFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, pemKeys...)
+	}
+	if len(cfg.HMACSecret) > 0 {
+		keys = append(keys, verificationKey{key: cfg.HMACSecret})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwt: no verification keys configured")
+	}
+
+	algorithms := cfg.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = DefaultJWTAlgorithms
+	}
+
+	extractors := cfg.Extractors
+	if len(extractors) == 0 {
+		extractors = []TokenExtractor{TokenFromBearer()}
+	}
+
+	principalClaim := cfg.PrincipalClaim
+	if principalClaim == "" {
+		principalClaim = "sub"
+	}
+	scopeClaim := cfg.ScopeClaim
+	if scopeClaim == "" {
+		scopeClaim = "scope"
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(algorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &JWTAuthenticator{
+		parser:         jwt.NewParser(parserOpts...),
+		keys:           keys,
+		extractors:     extractors,
+		principalClaim: principalClaim,
+		scopeClaim:     scopeClaim,
+	}, nil
+}
+
+// Name implements Authenticator
+func (a *JWTAuthenticator) Name() string {
+	return "jwt"
+}
+
+// Challenge returns the WWW-Authenticate challenge for 401 responses
+func (a *JWTAuthenticator) Challenge() string {
+	return `Bearer error="invalid_token"`
+}
+
+// Authenticate implements Authenticator
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (context.Context, error) {
+	ctx := r.Context()
+
+	rawToken := extractToken(r, a.extractors)
+	if rawToken == "" {
+		return nil, &AuthError{Method: a.Name(), Reason: AuthFailureMissing}
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (any, error) {
+		return selectVerificationKey(a.keys, token)
+	})
+	if err != nil {
+		return nil, &AuthError{Method: a.Name(), Reason: jwtFailureReason(err), Err: err}
+	}
+
+	principal, _ := claims[a.principalClaim].(string)
+	if principal == "" {
+		return nil, &AuthError{Method: a.Name(), Reason: AuthFailureInvalidToken,
+			Err: fmt.Errorf("missing %q claim", a.principalClaim)}
+	}
+
+	ctx = context.WithValue(ctx, claimsCtxKey{}, claims)
+	ctx = setPrincipal(ctx, principal)
+	ctx = WithScopes(ctx, claimScopes(claims[a.scopeClaim]))
+	return ctx, nil
+}
+
+// JWTMiddleware returns a middleware that authenticates requests with a JWT
+// bearer token. Valid tokens must carry "exp"; "nbf", "iss" and "aud" are
+// checked when present or configured. The claims are stored in the request
+// context (see ClaimsFromContext), the principal claim becomes the request
+// principal and the scope claim the granted scopes, so RequireScopes works
+// the same as with API keys.
+//
+// It can be added to the whole app by name:
+//
+//	jwtMiddleware, err := app.JWTMiddleware(app.JWTConfig{JWKSFile: "jwks.json", Issuer: "https://auth.example.com"})
+//	stack := app.DefaultMiddlewareStack().
+//	    InsertAfter("request-id", "jwt", jwtMiddleware).
+//	    Build()
+func JWTMiddleware(cfg JWTConfig) (Middleware, error) {
+	a, err := NewJWTAuthenticator(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return Authenticate(a), nil
+}
+
+// selectVerificationKey returns every key that may have signed the token:
+// keys whose type matches the signing algorithm and whose "kid" matches the
+// token's (keys without a kid always qualify). The parser tries each of them,
+// so tokens signed by any key of a rotation set verify.
+func selectVerificationKey(keys []verificationKey, token *jwt.Token) (any, error) {
+	alg := token.Method.Alg()
+	kid, _ := token.Header["kid"].(string)
+
+	var candidates []jwt.VerificationKey
+	for _, k := range keys {
+		if kid != "" && k.kid != "" && k.kid != kid {
+			continue
+		}
+		if k.alg != "" && k.alg != alg {
+			continue
+		}
+		if keyMatchesAlg(k.key, alg) {
+			candidates = append(candidates, k.key)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no key found for alg %q kid %q", alg, kid)
+	}
+	return jwt.VerificationKeySet{Keys: candidates}, nil
+}
+
+// keyMatchesAlg reports whether the key type can verify the given algorithm
+func keyMatchesAlg(key any, alg string) bool {
+	switch key.(type) {
+	case []byte:
+		return strings.HasPrefix(alg, "HS")
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		return strings.HasPrefix(alg, "ES")
+	default:
+		return false
+	}
+}
+
+// jwtFailureReason maps a jwt parse error to an auth failure reason
+func jwtFailureReason(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return AuthFailureExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		return AuthFailureNotYetValid
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return AuthFailureInvalidIssuer
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return AuthFailureInvalidAudience
+	default:
+		return AuthFailureInvalidToken
+	}
+}
+
+// claimScopes converts a scope claim (space separated string or list) to a slice
+func claimScopes(claim any) []string {
+	switch v := claim.(type) {
+	case string:
+		return splitScopes(v)
+	case []any:
+		scopes := make([]string, 0, len(v))
+		for _, s := range v {
+			if str, ok := s.(string); ok {
+				scopes = append(scopes, str)
+			}
+		}
+		return scopes
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestJWTAuthenticatorTriesAllKeysWithoutKid(t *testing.T) {
+	var keys []*rsa.PrivateKey
+	var pemData []byte
+	for i := 0; i < 2; i++ {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+		pemData = append(pemData, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})...)
+	}
+	pemFile := filepath.Join(t.TempDir(), "keys.pem")
+	if err := os.WriteFile(pemFile, pemData, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewJWTAuthenticator(JWTConfig{PEMFile: pemFile})
+	if err != nil {
+		t.Fatalf("NewJWTAuthenticator: %v", err)
+	}
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		key    *rsa.PrivateKey
+		reason string
+	}{
+		{"first key", keys[0], ""},
+		{"second key", keys[1], ""},
+		{"unknown key", other, AuthFailureInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+				"sub": "alice",
+				"exp": time.Now().Add(time.Minute).Unix(),
+			}).SignedString(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Authorization", "Bearer "+token)
+			ctx, err := a.Authenticate(r)
+
+			if tt.reason != "" {
+				var authErr *AuthError
+				if !errors.As(err, &authErr) || authErr.Reason != tt.reason {
+					t.Fatalf("err = %v, want reason %q", err, tt.reason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if p, _ := PrincipalFromContext(ctx); p != "alice" {
+				t.Errorf("principal = %q, want alice", p)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultHookTimeout is the timeout of lifecycle hooks that don't set one
+const DefaultHookTimeout = 15 * time.Second
+
+// Hook is a lifecycle hook. It should respect ctx cancellation; a hook that
+// outlives its timeout is abandoned and reported as failed.
+type Hook func(ctx context.Context) error
+
+// HookOption configures a lifecycle hook
+type HookOption func(*hook)
+
+// HookTimeout sets the timeout of a hook (default DefaultHookTimeout)
+func HookTimeout(d time.Duration) HookOption {
+	return func(h *hook) {
+		h.timeout = d
+	}
+}
+
+type hook struct {
+	name    string
+	fn      Hook
+	timeout time.Duration
+	// seq is the registration order across all phases
+	seq int
+}
+
+// lifecycle holds the hooks registered on an App
+type lifecycle struct {
+	mu         sync.Mutex
+	seq        int
+	onStart    []hook
+	onReady    []hook
+	onShutdown []hook
+}
+
+func (l *lifecycle) add(phase *[]hook, name string, fn Hook, opts []HookOption) {
+	h := hook{name: name, fn: fn, timeout: DefaultHookTimeout}
+	for _, opt := range opts {
+		opt(&h)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.seq++
+	h.seq = l.seq
+	*phase = append(*phase, h)
+}
+
+// OnStart registers a hook that runs before the servers start serving, e.g.
+// to open database pools or start background workers. The listeners are
+// already bound, so bind errors are reported before any hook runs;
+// connections made meanwhile wait in the accept backlog until all start hooks
+// have completed. Hooks run in
+// registration order; if one fails, the remaining start hooks are skipped,
+// the shutdown hooks registered before the failing hook run, and Run returns
+// the error. The hook's ctx is cancelled when the hook returns, so background
+// workers must not use it for their lifetime.
+func (app *App) OnStart(name string, fn Hook, opts ...HookOption) {
+	app.lifecycle.add(&app.lifecycle.onStart, name, fn, opts)
+}
+
+// OnReady registers a hook that runs once the servers have started, in
+// registration order. Failures are logged but do not stop the app.
+func (app *App) OnReady(name string, fn Hook, opts ...HookOption) {
+	app.lifecycle.add(&app.lifecycle.onReady, name, fn, opts)
+}
+
+// OnShutdown registers a hook that runs after the servers have stopped, e.g.
+// to close database pools. Shutdown hooks run in reverse registration order,
+// so resources are released in the opposite order they were acquired. All
+// hooks run even if some fail; the errors are joined.
+func (app *App) OnShutdown(name string, fn Hook, opts ...HookOption) {
+	app.lifecycle.add(&app.lifecycle.onShutdown, name, fn, opts)
+}
+
+// runStartHooks runs the start hooks in order and stops at the first failure,
+// releasing what has been started so far
+func (app *App) runStartHooks(ctx context.Context) error {
+	app.lifecycle.mu.Lock()
+	hooks := append([]hook(nil), app.lifecycle.onStart...)
+	app.lifecycle.mu.Unlock()
+
+	for _, h := range hooks {
+		if err := runHook(ctx, "start", h); err != nil {
+			if shutdownErr := app.runShutdownHooks(ctx, h.seq); shutdownErr != nil {
+				err = errors.Join(err, shutdownErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// runReadyHooks runs the ready hooks in order, logging failures
+func (app *App) runReadyHooks(ctx context.Context) {
+	app.lifecycle.mu.Lock()
+	hooks := append([]hook(nil), app.lifecycle.onReady...)
+	app.lifecycle.mu.Unlock()
+
+	for _, h := range hooks {
+		runHook(ctx, "ready", h)
+	}
+}
+
+// runShutdownHooks runs, in reverse order, the shutdown hooks registered
+// before seq (all of them if seq is 0) and joins their errors
+func (app *App) runShutdownHooks(ctx context.Context, seq int) error {
+	app.lifecycle.mu.Lock()
+	hooks := append([]hook(nil), app.lifecycle.onShutdown...)
+	app.lifecycle.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if seq > 0 && hooks[i].seq > seq {
+			continue
+		}
+		if err := runHook(ctx, "shutdown", hooks[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runHook runs one hook with its timeout, recovering panics
+func runHook(ctx context.Context, phase string, h hook) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rvr := recover(); rvr != nil {
+				done <- fmt.Errorf("panic: %v", rvr)
+			}
+		}()
+		done <- h.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", h.timeout)
+	}
+
+	duration := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		slog.Error("Lifecycle hook failed", "phase", phase, "hook", h.name, "duration", duration, "err", err)
+		return fmt.Errorf("%s hook %q: %w", phase, h.name, err)
+	}
+	slog.Info("Lifecycle hook completed", "phase", phase, "hook", h.name, "duration", duration)
+	return nil
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hookLog records the order lifecycle hooks ran in
+type hookLog struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (l *hookLog) hook(name string, err error) Hook {
+	return func(ctx context.Context) error {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.calls = append(l.calls, name)
+		return err
+	}
+}
+
+func (l *hookLog) expect(t *testing.T, want ...string) {
+	t.Helper()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !reflect.DeepEqual(l.calls, want) {
+		t.Errorf("hooks ran %v, want %v", l.calls, want)
+	}
+}
+
+func TestLifecycleHookOrder(t *testing.T) {
+	var log hookLog
+	a := newTestApp()
+	a.OnStart("db", log.hook("start db", nil))
+	a.OnShutdown("db", log.hook("stop db", nil))
+	a.OnStart("workers", log.hook("start workers", nil))
+	a.OnShutdown("workers", log.hook("stop workers", nil))
+	a.OnReady("announce", log.hook("ready", nil))
+
+	srv, _ := startServer(t, a)
+	log.expect(t, "start db", "start workers", "ready")
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	log.expect(t, "start db", "start workers", "ready", "stop workers", "stop db")
+}
+
+func TestStartHookFailureRollsBack(t *testing.T) {
+	var log hookLog
+	a := newTestApp()
+	a.OnStart("db", log.hook("start db", nil))
+	a.OnShutdown("db", log.hook("stop db", nil))
+	a.OnStart("cache", log.hook("start cache", errors.New("connection refused")))
+	a.OnShutdown("cache", log.hook("stop cache", nil))
+	a.OnStart("workers", log.hook("start workers", nil))
+	a.OnReady("announce", log.hook("ready", nil))
+
+	srv := &Server{App: a}
+	if _, err := srv.Start(context.Background()); err == nil || !strings.Contains(err.Error(), `start hook "cache": connection refused`) {
+		t.Fatalf("Start error = %v, want the cache hook error", err)
+	}
+	// Only what was started before the failing hook is released
+	log.expect(t, "start db", "start cache", "stop db")
+}
+
+func TestHookTimeoutAndPanic(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	a := newTestApp()
+	a.OnShutdown("stuck", func(ctx context.Context) error {
+		<-release
+		return nil
+	}, HookTimeout(20*time.Millisecond))
+	a.OnShutdown("broken", func(ctx context.Context) error {
+		panic("nil pool")
+	})
+
+	// Shutdown hooks all run and their errors are joined
+	err := a.runShutdownHooks(context.Background(), 0)
+	if err == nil {
+		t.Fatal("runShutdownHooks = nil, want errors")
+	}
+	for _, want := range []string{`shutdown hook "broken": panic: nil pool`, `shutdown hook "stuck": timed out after 20ms`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %q, want it to contain %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Names of socket activation listeners (LISTEN_FDNAMES, e.g. systemd's
+// FileDescriptorName=). Listeners with other names are used for the main server.
+const (
+	ListenerHTTP     = "http"
+	ListenerMetrics  = "metrics"
+	ListenerRedirect = "redirect"
+)
+
+// listenFdsStart is the first file descriptor passed by socket activation
+const listenFdsStart = 3
+
+// activationListeners returns the listeners passed by systemd-style socket
+// activation (LISTEN_PID, LISTEN_FDS, LISTEN_FDNAMES) or by the parent of a
+// graceful restart, keyed by server name. It returns nil if no listeners were
+// passed to this process. The variables are unset so child processes don't
+// inherit them.
+func activationListeners() (map[string]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if (err != nil || pid != os.Getpid()) && !restartChild() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+
+	var names []string
+	if fdNames := os.Getenv("LISTEN_FDNAMES"); fdNames != "" {
+		names = strings.Split(fdNames, ":")
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make(map[string]net.Listener, n)
+	closeAll := func() {
+		for _, ln := range listeners {
+			ln.Close()
+		}
+	}
+	for i := 0; i < n; i++ {
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+		if name != ListenerMetrics && name != ListenerRedirect {
+			name = ListenerHTTP
+		}
+		if _, dup := listeners[name]; dup {
+			closeAll()
+			return nil, fmt.Errorf("socket activation: more than one %s listener", name)
+		}
+
+		f := os.NewFile(uintptr(listenFdsStart+i), "listen-fd-"+name)
+		ln, err := net.FileListener(f)
+		f.Close() // FileListener duplicates the descriptor
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("socket activation: fd %d: %w", listenFdsStart+i, err)
+		}
+		listeners[name] = ln
+	}
+
+	slog.Info("Using socket activation listeners", "count", n, "names", names)
+	return listeners, nil
+}
+
+// listenUnix listens on a Unix domain socket at path and sets its file mode.
+// With cleanup, a stale socket left by a previous run (one that refuses
+// connections) is removed first and the socket file is removed when the
+// listener is closed. A socket another process still listens on is left alone.
+func listenUnix(path string, mode fs.FileMode, cleanup bool) (net.Listener, error) {
+	if cleanup {
+		if info, err := os.Lstat(path); err == nil {
+			if info.Mode().Type() != fs.ModeSocket {
+				return nil, fmt.Errorf("unix socket %s: file exists and is not a socket", path)
+			}
+			if err := removeStaleUnixSocket(path); err != nil {
+				return nil, err
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("unix socket %s: %w", path, err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(cleanup)
+
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("chmod unix socket: %w", err)
+		}
+	}
+	return ln, nil
+}
+
+// removeStaleUnixSocket removes the socket file at path unless a process is
+// still listening on it
+func removeStaleUnixSocket(path string) error {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("unix socket %s: address already in use", path)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("unix socket %s: %w", path, err)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("remove stale unix socket: %w", err)
+	}
+	return nil
+}
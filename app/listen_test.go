@@ -0,0 +1,41 @@
+package app
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListenUnixStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+
+	// Leave a socket file behind without anyone listening on it
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	if _, err := os.Lstat(path); err != nil {
+		t.Fatalf("stale socket missing: %v", err)
+	}
+
+	ln, err := listenUnix(path, 0, true)
+	if err != nil {
+		t.Fatalf("listenUnix over stale socket: %v", err)
+	}
+	defer ln.Close()
+
+	// A socket in use must not be taken over
+	_, err = listenUnix(path, 0, true)
+	if err == nil || !strings.Contains(err.Error(), "address already in use") {
+		t.Fatalf("listenUnix over live socket: err = %v, want address already in use", err)
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("live socket was removed: %v", err)
+	}
+	conn.Close()
+}
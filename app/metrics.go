@@ -0,0 +1,35 @@
+package app
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Application-level Prometheus collectors. They are registered on the default
+// registry, which is what the /metrics endpoint serves in both metrics modes.
+var (
+	authFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_auth_failures_total",
+		Help: "Number of requests rejected by authentication middleware, by method and reason.",
+	}, []string{"method", "reason"})
+
+	panicsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_panics_total",
+		Help: "Number of panics recovered from HTTP handlers, by route pattern.",
+	}, []string{"route"})
+
+	requestsInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Number of requests currently being served, by route pattern.",
+	}, []string{"route"})
+
+	requestsShedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_shed_total",
+		Help: "Number of requests rejected because a concurrency limit was reached, by route pattern and limit (global or route).",
+	}, []string{"route", "limit"})
+
+	serverDraining = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_server_draining",
+		Help: "1 while the server is in its pre-shutdown drain period, 0 otherwise.",
+	})
+)
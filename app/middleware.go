@@ -39,21 +39,23 @@ func NewMiddlewareStack() *MiddlewareStackBuilder {
 // Order matters! Middleware are applied in the order they're added.
 //
 // Default stack:
-//   - request-id: Injects a request ID into the context
+//   - request-id: Injects a request ID into the context and the X-Request-Id response header
 //   - real-ip: Sets a http.Request's RemoteAddr to either X-Forwarded-For or X-Real-IP
-//   - recoverer: Recovers from panics, logs the panic, and returns a HTTP 500 status
+//   - recoverer: Recovers from panics, logs the panic, and returns a HTTP 500 JSON error
 //   - version: Adds version information to response headers
 //   - http-logger: HTTP request/response logger (enabled via WithHTTPLogger)
 //   - cors: Cross-Origin Resource Sharing (enabled via WithCORS)
+//   - ratelimit: Token bucket rate limiting (enabled via WithRateLimit)
+//   - concurrency-limit: In-flight request limits and load shedding (enabled via WithConcurrencyLimit)
 //   - no-cache: Sets response headers to prevent clients from caching
 //   - hsts: HTTP Strict Transport Security headers (enabled via WithHSTS)
 //   - metrics: Prometheus metrics collection (enabled via WithMetrics)
 func DefaultMiddlewareStack() *MiddlewareStackBuilder {
 	return NewMiddlewareStack().
 		// Core request tracking
-		Add("request-id", middleware.RequestID).
+		Add("request-id", RequestID).
 		Add("real-ip", middleware.RealIP).
-		Add("recoverer", middleware.Recoverer).
+		Add("recoverer", Recoverer).
 
 		// Version tracking
 		Add("version", Version(Commit)).
@@ -64,6 +66,12 @@ func DefaultMiddlewareStack() *MiddlewareStackBuilder {
 		// CORS (placeholder - will be configured via WithCORS)
 		AddIf("cors", nil, false).
 
+		// Rate limiting (placeholder - will be configured via WithRateLimit)
+		AddIf("ratelimit", nil, false).
+
+		// Load shedding (placeholder - will be configured via WithConcurrencyLimit)
+		AddIf("concurrency-limit", nil, false).
+
 		// Security
 		Add("no-cache", middleware.NoCache).
 
@@ -78,8 +86,8 @@ func DefaultMiddlewareStack() *MiddlewareStackBuilder {
 // Useful as a starting point for building custom stacks.
 func MinimalMiddlewareStack() *MiddlewareStackBuilder {
 	return NewMiddlewareStack().
-		Add("request-id", middleware.RequestID).
-		Add("recoverer", middleware.Recoverer)
+		Add("request-id", RequestID).
+		Add("recoverer", Recoverer)
 }
 
 // Add appends a middleware to the end of the stack
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+)
+
+// Client certificate verification modes for TLSConfig.ClientAuth
+const (
+	// ClientAuthNone does not ask for client certificates
+	ClientAuthNone = "none"
+	// ClientAuthRequest asks for a client certificate and verifies it if one
+	// is sent; requests without one still reach the handlers, where
+	// ClientCertMiddleware or AnyOf decide whether that is acceptable
+	ClientAuthRequest = "request"
+	// ClientAuthRequire rejects TLS handshakes without a valid client certificate
+	ClientAuthRequire = "require"
+)
+
+// tlsClientAuth maps a verification mode to tls.ClientAuthType.
+// The mode defaults to "require" when a client CA bundle is configured.
+func tlsClientAuth(mode, caFile string) (tls.ClientAuthType, error) {
+	if mode == "" {
+		if caFile == "" {
+			mode = ClientAuthNone
+		} else {
+			mode = ClientAuthRequire
+		}
+	}
+
+	switch mode {
+	case ClientAuthNone:
+		return tls.NoClientCert, nil
+	case ClientAuthRequest:
+		return tls.VerifyClientCertIfGiven, nil
+	case ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return 0, fmt.Errorf("invalid TLS client auth: %s (must be 'none', 'request' or 'require')", mode)
+	}
+}
+
+// loadCertPool reads a PEM bundle of CA certificates
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("client CA file %s contains no PEM certificates", path)
+	}
+	return pool, nil
+}
+
+// ClientCertConfig configures client certificate authentication
+type ClientCertConfig struct {
+	// PrincipalFunc maps the verified client certificate to a principal
+	// (default ClientCertPrincipal)
+	PrincipalFunc func(cert *x509.Certificate) string
+	// Allowed restricts the accepted principals; empty accepts any
+	// certificate signed by the client CA
+	Allowed []string
+	// Scopes grants scopes per principal, for use with RequireScopes
+	Scopes map[string][]string
+}
+
+// ClientCertPrincipal returns the certificate's subject common name, falling
+// back to its first DNS, URI (e.g. a SPIFFE ID) or email SAN
+func ClientCertPrincipal(cert *x509.Certificate) string {
+	switch {
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0]
+	default:
+		return ""
+	}
+}
+
+// ClientCertAuthenticator authenticates requests by the TLS client
+// certificate verified during the handshake (see TLSConfig.ClientCAFile).
+// Create it with NewClientCertAuthenticator.
+type ClientCertAuthenticator struct {
+	cfg ClientCertConfig
+}
+
+// NewClientCertAuthenticator creates an Authenticator from the client certificate configuration
+func NewClientCertAuthenticator(cfg ClientCertConfig) *ClientCertAuthenticator {
+	if cfg.PrincipalFunc == nil {
+		cfg.PrincipalFunc = ClientCertPrincipal
+	}
+	return &ClientCertAuthenticator{cfg: cfg}
+}
+
+// Name implements Authenticator
+func (a *ClientCertAuthenticator) Name() string {
+	return "mtls"
+}
+
+// Authenticate implements Authenticator. Only certificates verified against
+// the client CA bundle are considered.
+func (a *ClientCertAuthenticator) Authenticate(r *http.Request) (context.Context, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, &AuthError{Method: a.Name(), Reason: AuthFailureMissing}
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+
+	principal := a.cfg.PrincipalFunc(cert)
+	if principal == "" {
+		return nil, &AuthError{Method: a.Name(), Reason: AuthFailureUnknown,
+			Err: fmt.Errorf("no principal in certificate %s", cert.Subject)}
+	}
+	if len(a.cfg.Allowed) > 0 && !slices.Contains(a.cfg.Allowed, principal) {
+		return nil, &AuthError{Method: a.Name(), Reason: AuthFailureUnknown, Principal: principal,
+			Err: fmt.Errorf("principal %q is not allowed", principal)}
+	}
+
+	ctx := setPrincipal(r.Context(), principal)
+	ctx = WithScopes(ctx, a.cfg.Scopes[principal])
+	return ctx, nil
+}
+
+// ClientCertMiddleware returns a middleware that only lets through requests
+// with a verified client certificate. Combine NewClientCertAuthenticator with
+// AnyOf to accept API keys or JWTs as well.
+func ClientCertMiddleware(cfg ClientCertConfig) Middleware {
+	return Authenticate(NewClientCertAuthenticator(cfg))
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"log/slog"
+	"os"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -38,12 +39,144 @@ func WithHost(host string) Option {
 	}
 }
 
-// WithLogger sets a custom slog logger
+// WithReadTimeout sets the maximum duration for reading an entire request, including the body
+func WithReadTimeout(d time.Duration) Option {
+	return func(a *App) {
+		a.Config.Server.ReadTimeout = d
+	}
+}
+
+// WithReadHeaderTimeout sets the maximum duration for reading request headers
+func WithReadHeaderTimeout(d time.Duration) Option {
+	return func(a *App) {
+		a.Config.Server.ReadHeaderTimeout = d
+	}
+}
+
+// WithWriteTimeout sets the maximum duration before timing out writes of the response
+func WithWriteTimeout(d time.Duration) Option {
+	return func(a *App) {
+		a.Config.Server.WriteTimeout = d
+	}
+}
+
+// WithIdleTimeout sets the maximum time to wait for the next request on keep-alive connections
+func WithIdleTimeout(d time.Duration) Option {
+	return func(a *App) {
+		a.Config.Server.IdleTimeout = d
+	}
+}
+
+// WithMaxHeaderBytes sets the maximum size of request headers
+func WithMaxHeaderBytes(n int) Option {
+	return func(a *App) {
+		a.Config.Server.MaxHeaderBytes = n
+	}
+}
+
+// WithUnixSocket makes the main server listen on a Unix domain socket at path
+// with the given file mode instead of Host:Port. A stale socket file is
+// removed at startup and the socket file is removed on shutdown.
+func WithUnixSocket(path string, mode os.FileMode) Option {
+	return func(a *App) {
+		a.Config.Listen.UnixSocket = path
+		a.Config.Listen.UnixSocketMode = mode
+		a.Config.Listen.UnixSocketCleanup = true
+	}
+}
+
+// WithSocketActivation uses listeners passed by systemd socket activation
+// (LISTEN_FDS) when present, falling back to the configured addresses
+func WithSocketActivation() Option {
+	return func(a *App) {
+		a.Config.Listen.SocketActivation = true
+	}
+}
+
+// WithDrainPeriod sets how long readiness fails after a shutdown signal
+// before the server stops accepting connections
+func WithDrainPeriod(d time.Duration) Option {
+	return func(a *App) {
+		a.Config.Server.DrainPeriod = d
+	}
+}
+
+// WithShutdownTimeout sets the maximum time graceful shutdown waits for
+// in-flight requests
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(a *App) {
+		a.Config.Server.ShutdownTimeout = d
+	}
+}
+
+// WithGracefulRestart enables zero-downtime restarts on SIGHUP: the binary is
+// re-executed with the listening sockets and this process shuts down once the
+// new one is ready (within timeout)
+func WithGracefulRestart(timeout time.Duration) Option {
+	return func(a *App) {
+		a.Config.Server.GracefulRestart = true
+		a.Config.Server.RestartTimeout = timeout
+	}
+}
+
+// WithTLS serves HTTPS using the given certificate and key files. The files
+// are reloaded when they change.
+func WithTLS(certFile, keyFile string) Option {
+	return func(a *App) {
+		a.Config.TLS.CertFile = certFile
+		a.Config.TLS.KeyFile = keyFile
+	}
+}
+
+// WithTLSMinVersion sets the minimum TLS version ("1.2" or "1.3")
+func WithTLSMinVersion(version string) Option {
+	return func(a *App) {
+		a.Config.TLS.MinVersion = version
+	}
+}
+
+// WithTLSCipherSuites restricts the TLS 1.2 cipher suites, by name
+func WithTLSCipherSuites(names ...string) Option {
+	return func(a *App) {
+		a.Config.TLS.CipherSuites = names
+	}
+}
+
+// WithClientCA enables mutual TLS: client certificates are verified against
+// the CA bundle in caFile. mode is "request" or "require" (see ClientAuthRequest
+// and ClientAuthRequire). Use ClientCertMiddleware to map the certificate to a
+// principal.
+func WithClientCA(caFile, mode string) Option {
+	return func(a *App) {
+		a.Config.TLS.ClientCAFile = caFile
+		a.Config.TLS.ClientAuth = mode
+	}
+}
+
+// WithHTTPSRedirect starts a plain HTTP listener on port that redirects all
+// requests to HTTPS. Requires WithTLS.
+func WithHTTPSRedirect(port int) Option {
+	return func(a *App) {
+		a.Config.TLS.RedirectPort = port
+	}
+}
+
+// WithLogger sets a custom slog logger and uses it to log recovered panics
 func WithLogger(logger *slog.Logger) Option {
 	return func(a *App) {
 		a.Logger = logger
 		// Set as default logger
 		slog.SetDefault(logger)
+
+		// Log panics through the app logger
+		if a.middlewareStack != nil {
+			for i, item := range a.middlewareStack.items {
+				if item.Name == "recoverer" {
+					a.middlewareStack.items[i].Middleware = NewRecoverer(logger)
+					break
+				}
+			}
+		}
 	}
 }
 
@@ -105,6 +238,42 @@ func WithDefaultCORS() Option {
 	return WithCORS(DefaultCorsOptions())
 }
 
+// WithRateLimit enables and configures the rate limiting middleware.
+// Note that it runs before route-level authentication, so RateLimitByPrincipal
+// only sees principals set by app-wide auth middleware; use
+// RateLimitMiddleware in a route group after auth otherwise.
+func WithRateLimit(cfg RateLimitConfig) Option {
+	return func(a *App) {
+		// Enable rate limiting in the middleware stack
+		if a.middlewareStack != nil {
+			for i, item := range a.middlewareStack.items {
+				if item.Name == "ratelimit" {
+					a.middlewareStack.items[i].Enabled = true
+					a.middlewareStack.items[i].Middleware = RateLimitMiddleware(cfg)
+					break
+				}
+			}
+		}
+	}
+}
+
+// WithConcurrencyLimit enables and configures the concurrency limiting
+// (load shedding) middleware
+func WithConcurrencyLimit(cfg ConcurrencyLimitConfig) Option {
+	return func(a *App) {
+		// Enable concurrency limiting in the middleware stack
+		if a.middlewareStack != nil {
+			for i, item := range a.middlewareStack.items {
+				if item.Name == "concurrency-limit" {
+					a.middlewareStack.items[i].Enabled = true
+					a.middlewareStack.items[i].Middleware = ConcurrencyLimitMiddleware(cfg)
+					break
+				}
+			}
+		}
+	}
+}
+
 // WithMetrics enables and configures Prometheus metrics middleware.
 // Uses combined mode by default (metrics on same server as app).
 // For separate server mode, use WithMetricsSeparatePort() instead.
@@ -244,7 +413,16 @@ func WithRouter(router *chi.Mux) Option {
 	}
 }
 
-// WithHttpin enables httpin integration for request parsing
+// WithErrorFormat sets the error response format: ErrorFormatJSON (default)
+// or ErrorFormatProblem for RFC 7807 application/problem+json
+func WithErrorFormat(format string) Option {
+	return func(a *App) {
+		a.Config.ErrorFormat = format
+	}
+}
+
+// WithHttpin enables httpin integration for request parsing. Decoding errors
+// are rendered in the standard JSON error format (see HttpinErrorHandler).
 func WithHttpin(enabled bool) Option {
 	return func(a *App) {
 		a.Config.UseHttpin = enabled
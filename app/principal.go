@@ -0,0 +1,60 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/go-chi/httplog/v2"
+)
+
+// principalCtxKey is the context key for the authenticated principal
+type principalCtxKey struct{}
+
+// AnonymousPrincipal is the label used when no principal is authenticated
+const AnonymousPrincipal = "anonymous"
+
+// WithPrincipal returns a copy of ctx carrying the authenticated principal name
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalCtxKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated principal name stored in ctx.
+// The second return value is false if the request was not authenticated.
+func PrincipalFromContext(ctx context.Context) (string, bool) {
+	principal, ok := ctx.Value(principalCtxKey{}).(string)
+	if !ok || principal == "" {
+		return "", false
+	}
+	return principal, true
+}
+
+// PrincipalLabel returns the principal name from ctx in a form that is safe to
+// use as a metrics label or log key: characters outside [a-zA-Z0-9_-] are
+// replaced with '_'. Unauthenticated requests return AnonymousPrincipal.
+func PrincipalLabel(ctx context.Context) string {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return AnonymousPrincipal
+	}
+	return sanitizeLabel(principal)
+}
+
+// setPrincipal stores the principal in the request context and adds it to the
+// httplog request log entry (if the http-logger middleware is enabled)
+func setPrincipal(ctx context.Context, principal string) context.Context {
+	httplog.LogEntrySetField(ctx, "principal", slog.StringValue(principal))
+	return WithPrincipal(ctx, principal)
+}
+
+// sanitizeLabel replaces characters outside [a-zA-Z0-9_-] with '_'
+func sanitizeLabel(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Error response formats
+const (
+	// ErrorFormatJSON renders errors in the README-error.md format (default)
+	ErrorFormatJSON = "json"
+	// ErrorFormatProblem renders errors as RFC 7807 application/problem+json
+	ErrorFormatProblem = "problem"
+)
+
+// ContentTypeProblemJSON is the media type of RFC 7807 problem details
+const ContentTypeProblemJSON = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	// Extensions are additional members serialized next to the standard ones
+	Extensions map[string]any `json:"-"`
+}
+
+// MarshalJSON flattens Extensions into the problem object
+func (p Problem) MarshalJSON() ([]byte, error) {
+	out := make(map[string]any, len(p.Extensions)+5)
+	maps.Copy(out, p.Extensions)
+
+	out["type"] = p.Type
+	out["title"] = p.Title
+	out["status"] = p.Status
+	if p.Detail != "" {
+		out["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		out["instance"] = p.Instance
+	}
+	return json.Marshal(out)
+}
+
+// Problem converts the error to RFC 7807 problem details. The error code and
+// nested errors are included as the "code" and "errors" extensions.
+func (e *APIError) Problem(r *http.Request) Problem {
+	status := e.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	problemType := e.Type
+	if problemType == "" {
+		problemType = "about:blank"
+	}
+
+	ext := make(map[string]any, len(e.Extensions)+2)
+	maps.Copy(ext, e.Extensions)
+	ext["code"] = e.Code
+	if len(e.Errors) > 0 {
+		ext["errors"] = e.Errors
+	}
+
+	return Problem{
+		Type:       problemType,
+		Title:      http.StatusText(status),
+		Status:     status,
+		Detail:     e.Message,
+		Instance:   middleware.GetReqID(r.Context()),
+		Extensions: ext,
+	}
+}
+
+// renderProblem writes the error as application/problem+json
+func renderProblem(w http.ResponseWriter, r *http.Request, e *APIError) {
+	problem := e.Problem(r)
+
+	body, err := json.Marshal(problem)
+	if err != nil {
+		slog.Error("Failed to render error response", "err", err)
+		w.WriteHeader(problem.Status)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentTypeProblemJSON)
+	w.WriteHeader(problem.Status)
+	w.Write(body)
+}
+
+// errorFormatCtxKey is the context key for the app's error format
+type errorFormatCtxKey struct{}
+
+// errorFormatMiddleware records the app's configured error format in the request context
+func errorFormatMiddleware(format string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), errorFormatCtxKey{}, format)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// errorFormat picks the error format for the request: whichever of
+// application/problem+json and application/json the Accept header explicitly
+// prefers, otherwise the app's configured format. Wildcards don't count as a
+// preference.
+func errorFormat(r *http.Request) string {
+	accept := r.Header.Values("Accept")
+	problemQ := acceptQuality(accept, ContentTypeProblemJSON)
+	jsonQ := acceptQuality(accept, "application/json")
+	switch {
+	case problemQ > jsonQ && problemQ > 0:
+		return ErrorFormatProblem
+	case jsonQ > problemQ && jsonQ > 0:
+		return ErrorFormatJSON
+	}
+
+	if format, ok := r.Context().Value(errorFormatCtxKey{}).(string); ok {
+		return format
+	}
+	return ErrorFormatJSON
+}
+
+// acceptQuality returns the quality value the Accept header values give the
+// media type by name, or -1 if they don't list it
+func acceptQuality(accept []string, mediaType string) float64 {
+	quality := -1.0
+	for _, value := range accept {
+		for _, part := range strings.Split(value, ",") {
+			mt, params, err := mime.ParseMediaType(part)
+			if err != nil || mt != mediaType {
+				continue
+			}
+			q := 1.0
+			if qs, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(qs, 64); err != nil || q < 0 || q > 1 {
+					continue
+				}
+			}
+			quality = max(quality, q)
+		}
+	}
+	return quality
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorFormat(t *testing.T) {
+	tests := []struct {
+		accept     string
+		configured string
+		want       string
+	}{
+		{"", "", ErrorFormatJSON},
+		{"", ErrorFormatProblem, ErrorFormatProblem},
+		{"*/*", ErrorFormatProblem, ErrorFormatProblem},
+		{"application/problem+json", "", ErrorFormatProblem},
+		{"application/json", ErrorFormatProblem, ErrorFormatJSON},
+		{"application/problem+json;q=0", "", ErrorFormatJSON},
+		{"application/problem+json;q=0", ErrorFormatProblem, ErrorFormatProblem},
+		{"application/json;q=0.5, application/problem+json", "", ErrorFormatProblem},
+		{"application/json, application/problem+json;q=0.9", ErrorFormatProblem, ErrorFormatJSON},
+		{"application/json, application/problem+json", ErrorFormatProblem, ErrorFormatProblem},
+		{"text/html, Application/Problem+JSON; q=0.8", "", ErrorFormatProblem},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		if tt.configured != "" {
+			r = r.WithContext(context.WithValue(r.Context(), errorFormatCtxKey{}, tt.configured))
+		}
+		if got := errorFormat(r); got != tt.want {
+			t.Errorf("Accept %q, configured %q: format = %q, want %q", tt.accept, tt.configured, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package app
+
+import (
+	"container/list"
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit describes a token bucket: Requests tokens are added every Per,
+// up to Burst tokens (defaults to Requests). Each request takes one token.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// PerSecond returns a limit of n requests per second
+func PerSecond(n int) RateLimit {
+	return RateLimit{Requests: n, Per: time.Second}
+}
+
+// PerMinute returns a limit of n requests per minute
+func PerMinute(n int) RateLimit {
+	return RateLimit{Requests: n, Per: time.Minute}
+}
+
+// capacity returns the bucket size
+func (l RateLimit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// rate returns the refill rate in tokens per second
+func (l RateLimit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// RateLimitResult is the outcome of taking a token from a bucket
+type RateLimitResult struct {
+	// Allowed reports whether the request may proceed
+	Allowed bool
+	// Limit is the bucket capacity
+	Limit int
+	// Remaining is the number of tokens left
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token is available (only set if not allowed)
+	RetryAfter time.Duration
+}
+
+// RateLimitStore holds token buckets. Implementations must be safe for
+// concurrent use; a shared store (e.g. Redis) makes limits apply across
+// multiple instances of the app.
+type RateLimitStore interface {
+	// Take takes one token from the bucket identified by key
+	Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error)
+}
+
+// RateLimitKeyFunc returns the key requests are grouped by for rate limiting.
+// Returning "" exempts the request from rate limiting.
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitByIP groups requests by client IP as seen in r.RemoteAddr.
+//
+// The default stack's real-ip middleware sets RemoteAddr from the
+// X-Forwarded-For and X-Real-IP headers of any client, so behind no proxy, or
+// a proxy that passes these headers through, clients can pick a new key for
+// every request and bypass the limit. Remove "real-ip" from the stack unless
+// a trusted proxy sets these headers, or use a KeyFunc that doesn't depend on
+// client-supplied headers.
+func RateLimitByIP(r *http.Request) string {
+	return "ip:" + remoteIP(r)
+}
+
+// RateLimitByPrincipal groups requests by authenticated principal (API key
+// name, JWT subject, ...), falling back to the client IP for anonymous
+// requests. It must run after the authentication middleware.
+func RateLimitByPrincipal(r *http.Request) string {
+	if principal, ok := PrincipalFromContext(r.Context()); ok {
+		return "principal:" + principal
+	}
+	return RateLimitByIP(r)
+}
+
+// RateLimitConfig configures RateLimitMiddleware
+type RateLimitConfig struct {
+	// Limit is applied to every key
+	Limit RateLimit
+	// Routes overrides Limit for chi route patterns, e.g. "/login".
+	// Each overridden route has its own bucket per key.
+	Routes map[string]RateLimit
+	// KeyFunc groups requests (default RateLimitByIP, which trusts
+	// X-Forwarded-For with the default stack; see its docs)
+	KeyFunc RateLimitKeyFunc
+	// Store holds the buckets (default: a new MemoryRateLimitStore)
+	Store RateLimitStore
+}
+
+// RateLimitMiddleware returns a token bucket rate limiting middleware.
+// Responses carry RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// headers; rejected requests get 429 Too Many Requests with Retry-After.
+// If the store fails, requests are let through.
+//
+// Example:
+//
+//	rl := app.RateLimitMiddleware(app.RateLimitConfig{
+//	    Limit:  app.PerSecond(10),
+//	    Routes: map[string]app.RateLimit{"/login": app.PerMinute(5)},
+//	})
+func RateLimitMiddleware(cfg RateLimitConfig) Middleware {
+	keyFunc := cfg.KeyFunc
+	if keyFunc == nil {
+		keyFunc = RateLimitByIP
+	}
+	store := cfg.Store
+	if store == nil {
+		store = NewMemoryRateLimitStore(0)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := keyFunc(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			limit := cfg.Limit
+			if len(cfg.Routes) > 0 {
+				route := routePattern(r)
+				if routeLimit, ok := cfg.Routes[route]; ok {
+					limit = routeLimit
+					key = "route:" + route + ":" + key
+				}
+			}
+			if limit.Requests <= 0 || limit.Per <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result, err := store.Take(r.Context(), key, limit)
+			if err != nil {
+				slog.Error("Rate limit store error", "key", key, "err", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+			if !result.Allowed {
+				h.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				slog.Warn("Rate limit exceeded", "key", key, "path", r.URL.Path)
+				RenderError(w, r, TooManyRequestsError("rate limit exceeded"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ceilSeconds rounds d up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// DefaultRateLimitMaxKeys is the default maximum number of buckets kept by MemoryRateLimitStore
+const DefaultRateLimitMaxKeys = 100_000
+
+// MemoryRateLimitStore is an in-process RateLimitStore. Buckets that have
+// refilled completely are evicted once a minute, and the least recently used
+// bucket is dropped when maxKeys is reached.
+type MemoryRateLimitStore struct {
+	maxKeys int
+	now     func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*list.Element // of *tokenBucket
+	lru       *list.List               // most recently used first
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+	limit  RateLimit
+}
+
+// NewMemoryRateLimitStore creates an in-memory store holding at most maxKeys
+// buckets (DefaultRateLimitMaxKeys if zero)
+func NewMemoryRateLimitStore(maxKeys int) *MemoryRateLimitStore {
+	if maxKeys <= 0 {
+		maxKeys = DefaultRateLimitMaxKeys
+	}
+	return &MemoryRateLimitStore{
+		maxKeys: maxKeys,
+		now:     time.Now,
+		buckets: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Take implements RateLimitStore
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > time.Minute {
+		s.sweep(now)
+	}
+
+	capacity, rate := limit.capacity(), limit.rate()
+
+	var b *tokenBucket
+	if elem, ok := s.buckets[key]; ok {
+		s.lru.MoveToFront(elem)
+		b = elem.Value.(*tokenBucket)
+		if b.limit != limit {
+			*b = tokenBucket{key: key, tokens: capacity, last: now, limit: limit}
+		}
+	} else {
+		if len(s.buckets) >= s.maxKeys {
+			s.remove(s.lru.Back())
+		}
+		b = &tokenBucket{key: key, tokens: capacity, last: now, limit: limit}
+		s.buckets[key] = s.lru.PushFront(b)
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	result := RateLimitResult{Limit: int(capacity)}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsDuration((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = secondsDuration((capacity - b.tokens) / rate)
+
+	return result, nil
+}
+
+// sweep removes buckets that have refilled completely; recreating them later
+// gives the same result
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for elem := s.lru.Front(); elem != nil; {
+		next := elem.Next()
+		b := elem.Value.(*tokenBucket)
+		if b.tokens+now.Sub(b.last).Seconds()*b.limit.rate() >= b.limit.capacity() {
+			s.remove(elem)
+		}
+		elem = next
+	}
+	s.lastSweep = now
+}
+
+// remove drops a bucket
+func (s *MemoryRateLimitStore) remove(elem *list.Element) {
+	delete(s.buckets, elem.Value.(*tokenBucket).key)
+	s.lru.Remove(elem)
+}
+
+func secondsDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeClock is a settable time source for stores
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestRateLimitStore(maxKeys int) (*MemoryRateLimitStore, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	s := NewMemoryRateLimitStore(maxKeys)
+	s.now = clock.now
+	return s, clock
+}
+
+func take(t *testing.T, s RateLimitStore, key string, limit RateLimit) RateLimitResult {
+	t.Helper()
+	result, err := s.Take(context.Background(), key, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestMemoryRateLimitStoreRefill(t *testing.T) {
+	s, clock := newTestRateLimitStore(0)
+	limit := RateLimit{Requests: 2, Per: time.Second, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		if r := take(t, s, "k", limit); !r.Allowed || r.Remaining != 2-i {
+			t.Fatalf("take %d = %+v, want allowed with %d remaining", i, r, 2-i)
+		}
+	}
+	r := take(t, s, "k", limit)
+	if r.Allowed || r.RetryAfter != 500*time.Millisecond || r.Reset != 1500*time.Millisecond {
+		t.Fatalf("take on empty bucket = %+v, want rejected, retry after 500ms, reset 1.5s", r)
+	}
+
+	clock.advance(500 * time.Millisecond)
+	if r := take(t, s, "k", limit); !r.Allowed || r.Remaining != 0 {
+		t.Fatalf("take after refill = %+v, want allowed with 0 remaining", r)
+	}
+
+	// Refilling never exceeds the burst
+	clock.advance(time.Hour)
+	if r := take(t, s, "k", limit); r.Remaining != 2 {
+		t.Fatalf("take after full refill = %+v, want 2 remaining", r)
+	}
+}
+
+func TestMemoryRateLimitStoreEviction(t *testing.T) {
+	s, clock := newTestRateLimitStore(2)
+	limit := PerMinute(1)
+
+	take(t, s, "a", limit)
+	take(t, s, "b", limit)
+	clock.advance(time.Second)
+	take(t, s, "a", limit) // rejected, but makes b the least recently used
+
+	take(t, s, "c", limit)
+	if len(s.buckets) != 2 {
+		t.Fatalf("%d buckets, want 2", len(s.buckets))
+	}
+	if _, ok := s.buckets["b"]; ok {
+		t.Error("least recently used bucket b was not evicted")
+	}
+	if r := take(t, s, "a", limit); r.Allowed {
+		t.Error("bucket a was evicted instead of b")
+	}
+
+	// Full buckets are swept once a minute
+	clock.advance(2 * time.Minute)
+	take(t, s, "d", limit)
+	if len(s.buckets) != 1 {
+		t.Errorf("%d buckets after sweep, want 1", len(s.buckets))
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	store, _ := newTestRateLimitStore(0)
+	r := chi.NewRouter()
+	r.Use(RateLimitMiddleware(RateLimitConfig{
+		Limit:  PerMinute(2),
+		Routes: map[string]RateLimit{"/login/{provider}": PerMinute(1)},
+		Store:  store,
+	}))
+	okHandler := func(w http.ResponseWriter, r *http.Request) {}
+	r.Get("/orders", okHandler)
+	r.Get("/login/{provider}", okHandler)
+
+	do := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("/orders", "192.0.2.1:1234")
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != "1" || w.Header().Get("RateLimit-Reset") != "30" {
+		t.Errorf("first request: %d %v", w.Code, w.Header())
+	}
+	do("/orders", "192.0.2.1:1234")
+	w = do("/orders", "192.0.2.1:5678")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("third request: %d %v", w.Code, w.Header())
+	}
+
+	// Other clients have their own buckets
+	if w := do("/orders", "192.0.2.2:1234"); w.Code != http.StatusOK {
+		t.Errorf("other client: %d", w.Code)
+	}
+
+	// Route overrides have their own limit and bucket, shared by all
+	// requests matching the pattern
+	if w := do("/login/github", "192.0.2.1:1234"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "1" {
+		t.Errorf("first login: %d %v", w.Code, w.Header())
+	}
+	if w := do("/login/google", "192.0.2.1:1234"); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("second login: %d %v", w.Code, w.Header())
+	}
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Recoverer is a middleware that recovers from panics, logs the panic with a
+// stack trace through the default slog logger and responds with a 500 JSON
+// error (see APIError).
+//
+// It replaces chi's middleware.Recoverer in the default middleware stack.
+// WithLogger switches the stack's recoverer to NewRecoverer(logger).
+func Recoverer(next http.Handler) http.Handler {
+	return NewRecoverer(nil)(next)
+}
+
+// NewRecoverer returns a Recoverer that logs panics to the given logger
+// (slog.Default() if nil) with the request ID, route pattern and stack trace
+// as structured attributes, and counts them in the http_panics_total metric.
+func NewRecoverer(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if rvr := recover(); rvr != nil {
+					if rvr == http.ErrAbortHandler {
+						// we don't recover http.ErrAbortHandler so the response
+						// to the client is aborted, this should not be logged
+						panic(rvr)
+					}
+
+					route := routePattern(r)
+					panicsTotal.WithLabelValues(route).Inc()
+
+					log := logger
+					if log == nil {
+						log = slog.Default()
+					}
+					log.ErrorContext(r.Context(), "Panic recovered",
+						"panic", fmt.Sprint(rvr),
+						"request_id", middleware.GetReqID(r.Context()),
+						"method", r.Method,
+						"path", r.URL.Path,
+						"route", route,
+						"stack", string(debug.Stack()),
+					)
+
+					if r.Header.Get("Connection") != "Upgrade" {
+						// Already logged above, so skip RenderError's logging
+						writeError(w, r, InternalError(panicError(rvr)))
+					}
+				}
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// panicError converts a recovered panic value to an error
+func panicError(rvr any) error {
+	if err, ok := rvr.(error); ok {
+		return fmt.Errorf("panic: %w", err)
+	}
+	return errors.New(fmt.Sprint("panic: ", rvr))
+}
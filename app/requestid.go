@@ -0,0 +1,19 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestID is chi's middleware.RequestID that also returns the request ID in
+// the X-Request-Id response header. It is the "instance" of problem details,
+// so clients can quote it when reporting problems.
+func RequestID(next http.Handler) http.Handler {
+	return middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := middleware.GetReqID(r.Context()); id != "" {
+			w.Header().Set(middleware.RequestIDHeader, id)
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
@@ -0,0 +1,176 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables set for the child process of a graceful restart
+const (
+	// restartChildEnv makes the child use the inherited listeners even
+	// without LISTEN_PID, which cannot be known before the child starts
+	restartChildEnv = "GRACEFUL_RESTART_CHILD"
+	// restartReadyFdEnv is the pipe the child reports readiness on
+	restartReadyFdEnv = "GRACEFUL_RESTART_READY_FD"
+)
+
+// DefaultRestartTimeout is how long a graceful restart waits for the child
+const DefaultRestartTimeout = 30 * time.Second
+
+// restartChild reports whether this process was started by a graceful restart
+func restartChild() bool {
+	return os.Getenv(restartChildEnv) == "1"
+}
+
+// restart re-executes the binary, passing the bound listeners as
+// socket-activation file descriptors, and waits until the child is ready.
+// If the child fails to become ready, or ctx is cancelled first, it is killed
+// and the parent keeps serving.
+func (s *Server) restart(ctx context.Context, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultRestartTimeout
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("find executable: %w", err)
+	}
+
+	var files []*os.File
+	var names []string
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	for _, l := range []struct {
+		name string
+		ln   net.Listener
+	}{
+		{ListenerHTTP, s.listeners.http},
+		{ListenerMetrics, s.listeners.metrics},
+		{ListenerRedirect, s.listeners.redirect},
+	} {
+		if l.ln == nil {
+			continue
+		}
+		f, err := listenerFile(l.ln)
+		if err != nil {
+			return fmt.Errorf("%s listener: %w", l.name, err)
+		}
+		files = append(files, f)
+		names = append(names, l.name)
+	}
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("create ready pipe: %w", err)
+	}
+	defer readyR.Close()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(restartEnviron(),
+		"LISTEN_FDS="+strconv.Itoa(len(files)),
+		"LISTEN_FDNAMES="+strings.Join(names, ":"),
+		restartChildEnv+"=1",
+		restartReadyFdEnv+"="+strconv.Itoa(listenFdsStart+len(files)),
+	)
+	cmd.ExtraFiles = append(append([]*os.File(nil), files...), readyW)
+
+	if err := cmd.Start(); err != nil {
+		readyW.Close()
+		return fmt.Errorf("start child: %w", err)
+	}
+	readyW.Close() // only the child writes
+	slog.Info("Started child process for graceful restart, waiting until ready", "pid", cmd.Process.Pid, "timeout", timeout)
+
+	ready := make(chan error, 1)
+	go func() {
+		line, err := bufio.NewReader(readyR).ReadString('\n')
+		if strings.TrimSpace(line) == "ready" {
+			ready <- nil
+			return
+		}
+		if err == nil {
+			err = fmt.Errorf("unexpected message %q", line)
+		}
+		ready <- fmt.Errorf("child exited before it was ready: %w", err)
+	}()
+
+	select {
+	case err = <-ready:
+	case <-time.After(timeout):
+		err = fmt.Errorf("child not ready after %s", timeout)
+	case <-ctx.Done():
+	}
+	if err == nil && ctx.Err() != nil {
+		err = errors.New("restart cancelled by shutdown")
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		go cmd.Wait()
+		return err
+	}
+
+	// The child serves the Unix socket now, so shutting down must not remove it
+	for _, ln := range []net.Listener{s.listeners.http, s.listeners.metrics, s.listeners.redirect} {
+		if unixLn, ok := ln.(*net.UnixListener); ok {
+			unixLn.SetUnlinkOnClose(false)
+		}
+	}
+
+	slog.Info("Child process ready, handing over", "pid", cmd.Process.Pid)
+	return cmd.Process.Release()
+}
+
+// restartEnviron returns the environment without socket activation and
+// restart variables inherited by this process
+func restartEnviron() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		switch key {
+		case "LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES", restartChildEnv, restartReadyFdEnv:
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
+
+// notifyRestartParent tells the parent of a graceful restart that this
+// process is serving. It does nothing if the process was not started by one.
+func notifyRestartParent() error {
+	fdStr := os.Getenv(restartReadyFdEnv)
+	if fdStr == "" {
+		return nil
+	}
+	os.Unsetenv(restartReadyFdEnv)
+	os.Unsetenv(restartChildEnv)
+
+	fd, err := strconv.Atoi(fdStr)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", restartReadyFdEnv, err)
+	}
+	f := os.NewFile(uintptr(fd), "restart-ready")
+	if f == nil {
+		return errors.New("invalid restart ready file descriptor")
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString("ready\n"); err != nil {
+		return fmt.Errorf("notify parent: %w", err)
+	}
+	slog.Info("Notified parent process of graceful restart", "ppid", os.Getppid())
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package app
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// listenerFile duplicates the listener's socket for a child process
+func listenerFile(ln net.Listener) (*os.File, error) {
+	filer, ok := ln.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, fmt.Errorf("%T cannot be passed to a child process", ln)
+	}
+	return filer.File()
+}
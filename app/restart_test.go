@@ -0,0 +1,177 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Environment variables configuring the child of the graceful restart tests
+const (
+	restartTestPidFileEnv    = "RESTART_TEST_PID_FILE"
+	restartTestReadyDelayEnv = "RESTART_TEST_READY_DELAY"
+)
+
+// TestMain runs the stand-in child server when the test binary is
+// re-executed by a graceful restart
+func TestMain(m *testing.M) {
+	if restartChild() {
+		runRestartChild()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runRestartChild serves GET /pid on the inherited listeners until killed.
+// It writes its pid to RESTART_TEST_PID_FILE and delays readiness by
+// RESTART_TEST_READY_DELAY.
+func runRestartChild() {
+	if pidFile := os.Getenv(restartTestPidFileEnv); pidFile != "" {
+		os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())), 0o600)
+	}
+
+	a := NewApp(WithHost("127.0.0.1"), WithPort(0))
+	a.R.Get("/pid", servePid)
+	if delay, err := time.ParseDuration(os.Getenv(restartTestReadyDelayEnv)); err == nil {
+		a.OnStart("delay", func(ctx context.Context) error {
+			time.Sleep(delay)
+			return nil
+		}, HookTimeout(2*delay))
+	}
+	if err := a.RunContext(context.Background()); err != nil {
+		os.Exit(1)
+	}
+}
+
+func servePid(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(strconv.Itoa(os.Getpid())))
+}
+
+// getPid returns the pid of the process serving base, or 0 on error
+func getPid(base string) int {
+	resp, err := http.Get(base + "/pid")
+	if err != nil {
+		return 0
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	pid, _ := strconv.Atoi(string(body))
+	return pid
+}
+
+// childPid waits for the restart child to write its pid file
+func childPid(t *testing.T, pidFile string) int {
+	t.Helper()
+	deadline := time.Now().Add(15 * time.Second)
+	for time.Now().Before(deadline) {
+		data, _ := os.ReadFile(pidFile)
+		if pid, err := strconv.Atoi(string(data)); err == nil && pid > 0 {
+			return pid
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("restart child did not start")
+	return 0
+}
+
+// processExited reports whether the process with pid has exited
+func processExited(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return true
+	}
+	return p.Signal(syscall.Signal(0)) != nil
+}
+
+// startRestartServer starts a server with graceful restart enabled and runs
+// handleSignals with the returned channel standing in for SIGHUP
+func startRestartServer(t *testing.T) (srv *Server, base string, ctx context.Context, cancel context.CancelFunc, hups chan os.Signal, done chan struct{}) {
+	t.Helper()
+	a := newTestApp(WithGracefulRestart(15 * time.Second))
+	a.R.Get("/pid", servePid)
+	srv, base = startServer(t, a)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	hups = make(chan os.Signal, 1)
+	done = make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.handleSignals(ctx, cancel, nil, hups)
+	}()
+	return srv, base, ctx, cancel, hups, done
+}
+
+func TestGracefulRestartHandsOver(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	t.Setenv(restartTestPidFileEnv, pidFile)
+
+	srv, base, ctx, _, hups, done := startRestartServer(t)
+	hups <- syscall.SIGHUP
+	pid := childPid(t, pidFile)
+	t.Cleanup(func() {
+		if p, err := os.FindProcess(pid); err == nil {
+			p.Kill()
+		}
+	})
+
+	select {
+	case <-done:
+	case <-time.After(20 * time.Second):
+		t.Fatal("restart did not complete")
+	}
+	if !srv.handedOff.Load() || ctx.Err() == nil {
+		t.Fatal("successful restart did not hand off and stop the parent")
+	}
+
+	// The parent stops accepting without draining or failing readiness
+	if err := srv.wait(ctx, nil); err != nil {
+		t.Fatalf("wait = %v, want nil", err)
+	}
+	if srv.App.Health.ShuttingDown() {
+		t.Error("readiness failed after the handoff")
+	}
+
+	// The child serves the shared socket
+	if got := getPid(base); got != pid {
+		t.Errorf("served by pid %d, want child %d", got, pid)
+	}
+}
+
+func TestGracefulRestartCancelledKillsChild(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	t.Setenv(restartTestPidFileEnv, pidFile)
+	t.Setenv(restartTestReadyDelayEnv, "1m")
+
+	srv, base, _, cancel, hups, done := startRestartServer(t)
+	hups <- syscall.SIGHUP
+	pid := childPid(t, pidFile)
+
+	// Shutting down while the child is not ready yet aborts the restart
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handleSignals did not return after cancellation")
+	}
+	if srv.handedOff.Load() {
+		t.Error("cancelled restart handed off")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !processExited(pid) {
+		if time.Now().After(deadline) {
+			t.Fatalf("child %d still running after the restart was cancelled", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := getPid(base); got != os.Getpid() {
+		t.Errorf("served by pid %d, want the parent %d", got, os.Getpid())
+	}
+}
@@ -0,0 +1,42 @@
+//go:build unix
+
+package app
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenerFile duplicates the listener's socket for a child process. Unlike
+// the listener's File method, the duplicate stays non-blocking when exec
+// passes it on: blocking mode is shared with the listener and would leave
+// its Accept, and so Shutdown, stuck in a system call.
+func listenerFile(ln net.Listener) (*os.File, error) {
+	sc, ok := ln.(syscall.Conn)
+	if !ok {
+		return nil, fmt.Errorf("%T cannot be passed to a child process", ln)
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var fd int
+	var dupErr error
+	err = rc.Control(func(sysfd uintptr) {
+		syscall.ForkLock.RLock()
+		defer syscall.ForkLock.RUnlock()
+		if fd, dupErr = syscall.Dup(int(sysfd)); dupErr == nil {
+			syscall.CloseOnExec(fd)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if dupErr != nil {
+		return nil, os.NewSyscallError("dup", dupErr)
+	}
+	return os.NewFile(uintptr(fd), ln.Addr().String()), nil
+}
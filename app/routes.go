@@ -25,13 +25,49 @@ func RegisterVersionRoutes(r chi.Router) {
 	})
 }
 
-// RegisterHealthzRoutes registers health check routes
+// RegisterHealthzRoutes registers health check routes: /healthz for liveness
+// and /healthz/ready for readiness. Readiness is backed by an empty registry,
+// so it never fails, not even during shutdown or the drain period.
+//
+// Deprecated: Use App.RegisterHealthzRoutes, which serves the checks
+// registered on App.Health and fails readiness when shutdown begins.
 func RegisterHealthzRoutes(r chi.Router) {
-	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		render.PlainText(w, r, http.StatusText(http.StatusOK))
-	})
+	registerHealthzRoutes(r, NewHealthRegistry())
+}
 
-	r.Get("/healthz/ready", func(w http.ResponseWriter, r *http.Request) {
-		render.PlainText(w, r, http.StatusText(http.StatusOK))
-	})
+// RegisterHealthzRoutes registers the health check routes on the app's
+// router, backed by App.Health
+func (app *App) RegisterHealthzRoutes() {
+	registerHealthzRoutes(app.R, app.Health)
+}
+
+func registerHealthzRoutes(r chi.Router, health *HealthRegistry) {
+	r.Get("/healthz", health.LivenessHandler())
+	r.Get("/healthz/ready", health.ReadinessHandler())
+}
+
+// routePattern returns the chi route pattern for the request. Middleware in
+// the app-wide stack runs before routing, so if the pattern has not been
+// resolved yet it is looked up in the router. Returns "unknown" if no route matches.
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return "unknown"
+	}
+
+	if pattern := rctx.RoutePattern(); pattern != "" {
+		return pattern
+	}
+
+	if rctx.Routes != nil {
+		path := r.URL.RawPath
+		if path == "" {
+			path = r.URL.Path
+		}
+		if pattern := rctx.Routes.Find(chi.NewRouteContext(), r.Method, path); pattern != "" {
+			return pattern
+		}
+	}
+
+	return "unknown"
 }
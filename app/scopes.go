@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// scopesCtxKey is the context key for the scopes granted to the principal
+type scopesCtxKey struct{}
+
+// WithScopes returns a copy of ctx carrying the scopes granted to the principal
+func WithScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, scopesCtxKey{}, scopes)
+}
+
+// ScopesFromContext returns the scopes granted to the authenticated principal
+func ScopesFromContext(ctx context.Context) []string {
+	scopes, _ := ctx.Value(scopesCtxKey{}).([]string)
+	return scopes
+}
+
+// HasScopes reports whether the principal in ctx has been granted all the given scopes
+func HasScopes(ctx context.Context, required ...string) bool {
+	granted := ScopesFromContext(ctx)
+	for _, scope := range required {
+		if !slices.Contains(granted, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// RequireScopes returns a middleware that only allows requests whose
+// authenticated principal has all the given scopes. It must run after an
+// authentication middleware such as ApiKeyMiddleware.
+//
+// Requests without a principal are rejected with 401 Unauthorized, requests
+// whose principal lacks a scope with 403 Forbidden.
+//
+// Example:
+//
+//	r.Group(func(r chi.Router) {
+//	    r.Use(apiKeyMiddleware)
+//	    r.With(app.RequireScopes("orders:write")).Post("/orders", createOrder)
+//	})
+func RequireScopes(scopes ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			principal, ok := PrincipalFromContext(ctx)
+			if !ok {
+				slog.Warn("Request rejected: not authenticated", "path", r.URL.Path, "required_scopes", scopes)
+				RenderError(w, r, UnauthorizedError("authentication required"))
+				return
+			}
+
+			if !HasScopes(ctx, scopes...) {
+				slog.Warn("Request rejected: missing scope",
+					"principal", principal,
+					"path", r.URL.Path,
+					"required_scopes", scopes,
+					"granted_scopes", ScopesFromContext(ctx),
+				)
+				RenderError(w, r, ForbiddenError("insufficient scope").WithErrors(missingScopeErrors(ctx, scopes)...))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// missingScopeErrors lists the required scopes the principal in ctx lacks
+func missingScopeErrors(ctx context.Context, required []string) []APIError {
+	granted := ScopesFromContext(ctx)
+
+	var errs []APIError
+	for _, scope := range required {
+		if !slices.Contains(granted, scope) {
+			errs = append(errs, APIError{Code: "missing_scope", Message: "missing scope " + scope})
+		}
+	}
+	return errs
+}
+
+// splitScopes parses a comma or space separated scope list
+func splitScopes(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
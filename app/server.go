@@ -2,11 +2,16 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -18,23 +23,157 @@ type Server struct {
 	App           *App
 	HTTPServer    *http.Server
 	MetricsServer *http.Server
+	// RedirectServer redirects plain HTTP to HTTPS (see TLSConfig.RedirectPort)
+	RedirectServer *http.Server
 
-	// Shutdown timeout (default: 5 seconds)
+	// Shutdown timeout (default: Config.Server.ShutdownTimeout, or 5 seconds)
 	ShutdownTimeout time.Duration
+
+	certs        *certReloader
+	listeners    *serverListeners
+	serveErrs    <-chan error
+	shutdownOnce sync.Once
+	shutdownErr  error
+	// handedOff is set once a graceful restart handed the listeners to a child
+	handedOff atomic.Bool
 }
 
-// Run starts the HTTP server and handles graceful shutdown.
-// It blocks until the server is shut down via signal (SIGINT, SIGTERM).
+// Run starts the servers and blocks until they are shut down via signal
+// (SIGINT, SIGTERM) or a server fails. It is RunContext with signal handling:
+// the first signal starts the drain period and graceful shutdown, a second
+// signal skips the rest of the drain period.
+//
+// With ServerConfig.GracefulRestart, SIGHUP re-executes the binary and hands
+// the listening sockets to the new process. Once it is ready, this process
+// stops accepting connections and shuts down after finishing in-flight
+// requests, without draining or failing readiness; if it fails, this process
+// keeps serving.
 func (s *Server) Run() error {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	hups := make(chan os.Signal, 1)
+	if s.App.Config.Server.GracefulRestart {
+		signal.Notify(hups, syscall.SIGHUP)
+		defer signal.Stop(hups)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := s.Start(ctx); err != nil {
+		return err
+	}
+
+	signalsDone := make(chan struct{})
+	go func() {
+		defer close(signalsDone)
+		s.handleSignals(ctx, cancel, sigs, hups)
+	}()
+
+	err := s.wait(ctx, sigs)
+
+	// Make sure a restart in progress has killed its child before exiting
+	cancel()
+	<-signalsDone
+	return err
+}
+
+// handleSignals cancels ctx on the first shutdown signal or after a
+// successful graceful restart. A restart still in progress when ctx is
+// cancelled is aborted; handleSignals returns once its child is killed.
+func (s *Server) handleSignals(ctx context.Context, cancel context.CancelFunc, sigs, hups <-chan os.Signal) {
+	var restartDone chan error
+	defer func() {
+		if restartDone != nil {
+			cancel()
+			if err := <-restartDone; err != nil {
+				slog.Warn("Graceful restart aborted", "err", err)
+			}
+		}
+	}()
+
+	for {
+		select {
+		case sig := <-sigs:
+			slog.Info("Received shutdown signal", "signal", sig)
+			cancel()
+			return
+		case sig := <-hups:
+			if restartDone != nil {
+				slog.Warn("Graceful restart already in progress", "signal", sig)
+				continue
+			}
+			slog.Info("Received restart signal", "signal", sig)
+			restartDone = make(chan error, 1)
+			go func() {
+				restartDone <- s.restart(ctx, s.App.Config.Server.RestartTimeout)
+			}()
+		case err := <-restartDone:
+			restartDone = nil
+			if err != nil {
+				slog.Error("Graceful restart failed, continuing to serve", "err", err)
+				continue
+			}
+			s.handedOff.Store(true)
+			cancel()
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// RunContext starts the servers and blocks until ctx is cancelled or a
+// server fails, then drains and shuts down gracefully. It does not handle
+// OS signals, so it can be used in tests or when embedding the app.
+//
+// It returns the error that stopped the servers (nil if ctx was cancelled)
+// joined with any shutdown errors.
+func (s *Server) RunContext(ctx context.Context) error {
+	return s.run(ctx, nil)
+}
+
+func (s *Server) run(ctx context.Context, skipDrain <-chan os.Signal) error {
+	if _, err := s.Start(ctx); err != nil {
+		return err
+	}
+	return s.wait(ctx, skipDrain)
+}
+
+// Start validates the configuration, binds the listeners, runs the App's
+// start hooks, starts serving and runs the ready hooks, then returns the
+// bound address of the main server (useful with port 0). Errors such as an
+// invalid configuration or a port already in use are returned before anything
+// is served. Stop the server with Shutdown.
+func (s *Server) Start(ctx context.Context) (net.Addr, error) {
+	if err := s.App.Config.Validate(); err != nil {
+		return nil, err
+	}
+
+	if s.ShutdownTimeout == 0 {
+		s.ShutdownTimeout = s.App.Config.Server.ShutdownTimeout
+	}
 	if s.ShutdownTimeout == 0 {
 		s.ShutdownTimeout = 5 * time.Second
 	}
 
 	// Create HTTP server
 	addr := fmt.Sprintf("%s:%d", s.App.Config.Host, s.App.Config.Port)
-	s.HTTPServer = &http.Server{
-		Addr:    addr,
-		Handler: s.App.R,
+	s.HTTPServer = s.newHTTPServer(addr, s.App.R)
+
+	tlsCfg := s.App.Config.TLS
+	if tlsCfg.Enabled() {
+		certs, err := newCertReloader(tlsCfg.CertFile, tlsCfg.KeyFile, tlsCfg.ReloadInterval)
+		if err != nil {
+			return nil, err
+		}
+		s.certs = certs
+		if s.HTTPServer.TLSConfig, err = newTLSConfig(tlsCfg, certs); err != nil {
+			certs.close()
+			return nil, err
+		}
 	}
 
 	// Handle metrics based on mode
@@ -46,88 +185,316 @@ func (s *Server) Run() error {
 			slog.Info("Metrics enabled", "mode", "combined", "path", s.App.Config.Metrics.Path, "addr", addr)
 
 		case "separate":
-			// Start separate metrics server with custom path support
+			// Serve metrics on a separate server with custom path support
 			metricsAddr := fmt.Sprintf("%s:%d", s.App.Config.Metrics.Host, s.App.Config.Metrics.Port)
 
 			// Create a router for the metrics server to support custom paths
 			metricsRouter := http.NewServeMux()
 			metricsRouter.Handle(s.App.Config.Metrics.Path, promhttp.Handler())
 
-			s.MetricsServer = &http.Server{
-				Addr:    metricsAddr,
-				Handler: metricsRouter,
-			}
-
-			go func() {
-				slog.Info("Starting metrics server", "mode", "separate", "addr", metricsAddr, "path", s.App.Config.Metrics.Path)
-				if err := s.MetricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-					slog.Error("Metrics server error", "err", err)
-				}
-			}()
+			s.MetricsServer = s.newHTTPServer(metricsAddr, metricsRouter)
 
 		default:
 			slog.Warn("Invalid metrics mode", "mode", s.App.Config.Metrics.Mode)
 		}
 	}
 
-	// Start main HTTP server
-	slog.Info("Starting HTTP server", "addr", addr)
+	// Plain HTTP to HTTPS redirect server; the handler is set once the HTTPS port is known
+	if tlsCfg.Enabled() && tlsCfg.RedirectPort > 0 {
+		redirectAddr := fmt.Sprintf("%s:%d", s.App.Config.Host, tlsCfg.RedirectPort)
+		s.RedirectServer = s.newHTTPServer(redirectAddr, nil)
+	}
+
+	// Bind all listeners before running hooks or serving, so bind errors are returned
+	listeners, err := s.listen()
+	if err != nil {
+		if s.certs != nil {
+			s.certs.close()
+		}
+		return nil, err
+	}
+	if s.RedirectServer != nil {
+		httpsPort := s.App.Config.Port
+		if addr, ok := listeners.http.Addr().(*net.TCPAddr); ok {
+			httpsPort = addr.Port
+		}
+		s.RedirectServer.Handler = httpsRedirectHandler(httpsPort)
+	}
+
+	// Run start hooks before accepting traffic
+	if err := s.App.runStartHooks(ctx); err != nil {
+		listeners.close()
+		if s.certs != nil {
+			s.certs.close()
+		}
+		return nil, err
+	}
+
+	s.listeners = listeners
+	s.serveErrs = s.serve(listeners)
+	s.App.runReadyHooks(ctx)
+
+	if err := notifyRestartParent(); err != nil {
+		slog.Error("Failed to notify parent of graceful restart", "err", err)
+	}
+
+	return listeners.http.Addr(), nil
+}
+
+// Addr returns the bound address of the main server, or nil before Start
+func (s *Server) Addr() net.Addr {
+	if s.listeners == nil {
+		return nil
+	}
+	return s.listeners.http.Addr()
+}
+
+// MetricsAddr returns the bound address of the separate metrics server, or
+// nil if metrics are not served separately
+func (s *Server) MetricsAddr() net.Addr {
+	if s.listeners == nil || s.listeners.metrics == nil {
+		return nil
+	}
+	return s.listeners.metrics.Addr()
+}
+
+// serverListeners holds the bound listeners of the servers
+type serverListeners struct {
+	http     net.Listener
+	metrics  net.Listener
+	redirect net.Listener
+}
+
+// close closes all bound listeners
+func (l *serverListeners) close() {
+	for _, ln := range []net.Listener{l.http, l.metrics, l.redirect} {
+		if ln != nil {
+			ln.Close()
+		}
+	}
+}
+
+// listen binds the listeners of all configured servers, using socket
+// activation listeners and the Unix socket when configured. On error, the
+// listeners bound so far are closed.
+func (s *Server) listen() (*serverListeners, error) {
+	cfg := s.App.Config.Listen
+
+	var activated map[string]net.Listener
+	if cfg.SocketActivation || restartChild() {
+		var err error
+		if activated, err = activationListeners(); err != nil {
+			return nil, err
+		}
+		if activated == nil {
+			slog.Warn("Socket activation enabled but no listeners were passed, using configured addresses")
+		}
+	}
+
+	l := &serverListeners{}
+	bind := func(name string, srv *http.Server, ln *net.Listener) error {
+		if activatedLn, ok := activated[name]; ok {
+			delete(activated, name)
+			if srv == nil {
+				activatedLn.Close()
+				slog.Warn("Ignoring socket activation listener for disabled server", "name", name)
+				return nil
+			}
+			*ln = activatedLn
+			return nil
+		}
+		if srv == nil {
+			return nil
+		}
+
+		var err error
+		if name == ListenerHTTP && cfg.UnixSocket != "" {
+			*ln, err = listenUnix(cfg.UnixSocket, cfg.UnixSocketMode, cfg.UnixSocketCleanup)
+		} else {
+			*ln, err = net.Listen("tcp", srv.Addr)
+		}
+		if err != nil {
+			l.close()
+			for _, activatedLn := range activated {
+				activatedLn.Close()
+			}
+			return fmt.Errorf("%s server: %w", name, err)
+		}
+		return nil
+	}
+
+	if err := bind(ListenerHTTP, s.HTTPServer, &l.http); err != nil {
+		return nil, err
+	}
+	if err := bind(ListenerMetrics, s.MetricsServer, &l.metrics); err != nil {
+		return nil, err
+	}
+	if err := bind(ListenerRedirect, s.RedirectServer, &l.redirect); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// serve starts serving on the bound listeners. Errors other than
+// http.ErrServerClosed are sent on the returned channel.
+func (s *Server) serve(l *serverListeners) <-chan error {
+	errs := make(chan error, 3)
+
+	if l.metrics != nil {
+		slog.Info("Starting metrics server", "mode", "separate", "addr", l.metrics.Addr().String(), "path", s.App.Config.Metrics.Path)
+		go func() {
+			if err := s.MetricsServer.Serve(l.metrics); err != nil && err != http.ErrServerClosed {
+				errs <- fmt.Errorf("metrics server: %w", err)
+			}
+		}()
+	}
+
+	if l.redirect != nil {
+		slog.Info("Starting HTTPS redirect server", "addr", l.redirect.Addr().String())
+		go func() {
+			if err := s.RedirectServer.Serve(l.redirect); err != nil && err != http.ErrServerClosed {
+				errs <- fmt.Errorf("redirect server: %w", err)
+			}
+		}()
+	}
+
+	if s.HTTPServer.TLSConfig != nil {
+		slog.Info("Starting HTTPS server", "addr", l.http.Addr().String(), "min_tls_version", s.App.Config.TLS.MinVersion)
+	} else {
+		slog.Info("Starting HTTP server", "addr", l.http.Addr().String())
+	}
 	go func() {
-		if err := s.HTTPServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			slog.Error("HTTP server error", "err", err)
+		var err error
+		if s.HTTPServer.TLSConfig != nil {
+			// Certificates come from TLSConfig.GetCertificate
+			err = s.HTTPServer.ServeTLS(l.http, "", "")
+		} else {
+			err = s.HTTPServer.Serve(l.http)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			errs <- fmt.Errorf("http server: %w", err)
 		}
 	}()
 
-	// Wait for interrupt signal
-	return s.waitForShutdown()
+	return errs
 }
 
-// waitForShutdown blocks until receiving SIGINT or SIGTERM, then gracefully shuts down
-func (s *Server) waitForShutdown() error {
-	// Create channel to listen for interrupt signals
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+// newHTTPServer creates an http.Server with the configured limits and timeouts
+func (s *Server) newHTTPServer(addr string, handler http.Handler) *http.Server {
+	cfg := s.App.Config.Server
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
 
-	// Block until signal received
-	sig := <-stop
-	slog.Info("Received shutdown signal", "signal", sig)
+// wait blocks until ctx is cancelled or a server fails, then shuts down. On
+// cancellation the drain period is observed first; a value on skipDrain cuts
+// it short. If a server fails, the others are shut down without draining and
+// the server error is returned.
+func (s *Server) wait(ctx context.Context, skipDrain <-chan os.Signal) error {
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		if s.handedOff.Load() {
+			// The child accepts on the shared sockets now and is ready, so
+			// stop accepting right away instead of draining
+			slog.Info("Handed over to new process, stopping accepting connections")
+		} else {
+			s.drain(skipDrain)
+		}
+	case serveErr = <-s.serveErrs:
+		slog.Error("Server failed, shutting down", "err", serveErr)
+	}
 
 	// Create context with timeout for shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
 	defer cancel()
 
-	// Shutdown HTTP server
-	if err := s.HTTPServer.Shutdown(ctx); err != nil {
-		slog.Error("HTTP server shutdown error", "err", err)
-		return err
-	}
-	slog.Info("HTTP server stopped gracefully")
+	return errors.Join(serveErr, s.Shutdown(shutdownCtx))
+}
 
-	// Shutdown metrics server if running
-	if s.MetricsServer != nil {
-		if err := s.MetricsServer.Shutdown(ctx); err != nil {
-			slog.Error("Metrics server shutdown error", "err", err)
-			return err
-		}
-		slog.Info("Metrics server stopped gracefully")
+// drain fails readiness and keeps serving for the configured drain period, so
+// load balancers deregister the instance before it stops accepting connections
+func (s *Server) drain(stop <-chan os.Signal) {
+	s.App.Health.SetShuttingDown()
+
+	period := s.App.Config.Server.DrainPeriod
+	if period <= 0 {
+		return
 	}
 
-	return nil
+	slog.Info("Draining before shutdown: readiness failing, still serving requests", "drain_period", period)
+	serverDraining.Set(1)
+	defer serverDraining.Set(0)
+
+	// Ask clients to reconnect, so they pick up a different instance
+	s.HTTPServer.SetKeepAlivesEnabled(false)
+
+	start := time.Now()
+	timer := time.NewTimer(period)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		slog.Info("Drain period over, shutting down", "drained_for", time.Since(start).Round(time.Millisecond))
+	case sig := <-stop:
+		slog.Warn("Received second shutdown signal, skipping rest of drain period", "signal", sig, "drained_for", time.Since(start).Round(time.Millisecond))
+	}
 }
 
-// Shutdown gracefully shuts down the server with the given context
+// Shutdown gracefully shuts down the servers with the given context, then runs
+// the App's shutdown hooks in reverse order. All servers and hooks are shut
+// down even if some fail; the errors are joined. Calling Shutdown again
+// returns the first result.
 func (s *Server) Shutdown(ctx context.Context) error {
-	if s.HTTPServer != nil {
-		if err := s.HTTPServer.Shutdown(ctx); err != nil {
-			return fmt.Errorf("http server shutdown: %w", err)
-		}
+	s.shutdownOnce.Do(func() {
+		s.shutdownErr = s.shutdown(ctx)
+	})
+	return s.shutdownErr
+}
+
+func (s *Server) shutdown(ctx context.Context) error {
+	// After a handoff readiness keeps passing: the sockets are served by the
+	// child, and in-flight requests here must not look like an outage
+	if !s.handedOff.Load() {
+		s.App.Health.SetShuttingDown()
 	}
 
-	if s.MetricsServer != nil {
-		if err := s.MetricsServer.Shutdown(ctx); err != nil {
-			return fmt.Errorf("metrics server shutdown: %w", err)
+	var errs []error
+	servers := []struct {
+		name string
+		srv  *http.Server
+	}{
+		{"HTTP", s.HTTPServer},
+		{"Metrics", s.MetricsServer},
+		{"Redirect", s.RedirectServer},
+	}
+	for _, server := range servers {
+		if server.srv == nil {
+			continue
 		}
+		if err := server.srv.Shutdown(ctx); err != nil {
+			slog.Error(server.name+" server shutdown error", "err", err)
+			errs = append(errs, fmt.Errorf("%s server shutdown: %w", strings.ToLower(server.name), err))
+			continue
+		}
+		slog.Info(server.name + " server stopped gracefully")
+	}
+
+	if s.certs != nil {
+		s.certs.close()
+	}
+
+	// Hooks have their own timeouts, so they still run when ctx has expired
+	if err := s.App.runShutdownHooks(context.WithoutCancel(ctx), 0); err != nil {
+		errs = append(errs, err)
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
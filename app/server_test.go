@@ -0,0 +1,254 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestStartValidatesConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr string
+	}{
+		{"error format", []Option{WithErrorFormat("xml")}, "invalid error format"},
+		{"timeout", []Option{WithReadTimeout(-1)}, "invalid server timeouts"},
+		{"max header bytes", []Option{WithMaxHeaderBytes(-1)}, "invalid max header bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewApp(append([]Option{WithHost("127.0.0.1"), WithPort(0)}, tt.opts...)...)
+			srv := &Server{App: a}
+			addr, err := srv.Start(context.Background())
+			if err == nil {
+				srv.Shutdown(context.Background())
+				t.Fatalf("Start = %v, want error", addr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Start error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// newTestApp creates an App listening on a free loopback port with /orders
+// and the health endpoints registered
+func newTestApp(opts ...Option) *App {
+	a := NewApp(append([]Option{WithHost("127.0.0.1"), WithPort(0)}, opts...)...)
+	a.RegisterHealthzRoutes()
+	a.R.Get("/orders", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("orders"))
+	})
+	return a
+}
+
+// startServer starts a Server for a and shuts it down when the test ends
+func startServer(t *testing.T, a *App) (*Server, string) {
+	t.Helper()
+	srv := &Server{App: a}
+	addr, err := srv.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+	return srv, "http://" + addr.String()
+}
+
+// getStatus returns the status code of a GET request, or 0 if it failed
+func getStatus(url string) int {
+	resp, err := http.Get(url)
+	if err != nil {
+		return 0
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestDrainFailsReadinessWhileServing(t *testing.T) {
+	srv, base := startServer(t, newTestApp(WithDrainPeriod(time.Minute)))
+	if code := getStatus(base + "/healthz/ready"); code != http.StatusOK {
+		t.Fatalf("readiness before shutdown = %d, want 200", code)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	skipDrain := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() { done <- srv.wait(ctx, skipDrain) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for getStatus(base+"/healthz/ready") != http.StatusServiceUnavailable {
+		if time.Now().After(deadline) {
+			t.Fatal("readiness did not fail during the drain period")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if code := getStatus(base + "/orders"); code != http.StatusOK {
+		t.Errorf("request during drain = %d, want 200", code)
+	}
+
+	// A second signal skips the rest of the drain period
+	skipDrain <- syscall.SIGTERM
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("wait = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("second signal did not cut the drain period short")
+	}
+	if code := getStatus(base + "/orders"); code != 0 {
+		t.Errorf("request after shutdown = %d, want connection error", code)
+	}
+}
+
+func TestBindErrorReturned(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	var started bool
+	a := newTestApp(WithPort(port))
+	a.OnStart("db", func(ctx context.Context) error {
+		started = true
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- a.RunContext(context.Background()) }()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "address already in use") {
+			t.Errorf("RunContext = %v, want address already in use", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("RunContext blocked on a port already in use")
+	}
+	if started {
+		t.Error("start hook ran despite the bind error")
+	}
+}
+
+func TestServeErrorShutsDown(t *testing.T) {
+	var stopped bool
+	a := newTestApp()
+	a.OnShutdown("db", func(ctx context.Context) error {
+		stopped = true
+		return nil
+	})
+	srv, _ := startServer(t, a)
+
+	// Closing the listener under the server makes Serve fail
+	srv.listeners.http.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- srv.wait(context.Background(), nil) }()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "http server") {
+			t.Errorf("wait = %v, want the http server error", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("serve error did not stop the server")
+	}
+	if !stopped {
+		t.Error("shutdown hooks did not run after the serve error")
+	}
+}
+
+func TestStartReturnsBoundAddresses(t *testing.T) {
+	// Set the config directly: WithMetricsSeparatePort registers collectors,
+	// which can only happen once per process
+	separateMetrics := func(a *App) {
+		a.Config.Metrics.Enabled = true
+		a.Config.Metrics.Mode = "separate"
+		a.Config.Metrics.Host = "127.0.0.1"
+		a.Config.Metrics.Port = 0
+	}
+	srv, base := startServer(t, newTestApp(separateMetrics))
+
+	addr := srv.Addr().(*net.TCPAddr)
+	if addr.Port == 0 || base != "http://"+addr.String() {
+		t.Errorf("Addr = %v, want the port bound by Start (%s)", addr, base)
+	}
+	if code := getStatus(base + "/orders"); code != http.StatusOK {
+		t.Errorf("GET /orders = %d, want 200", code)
+	}
+
+	metricsAddr := srv.MetricsAddr().(*net.TCPAddr)
+	if metricsAddr.Port == 0 || metricsAddr.Port == addr.Port {
+		t.Fatalf("MetricsAddr = %v, want a separate bound port", metricsAddr)
+	}
+	if code := getStatus(fmt.Sprintf("http://127.0.0.1:%d/metrics", metricsAddr.Port)); code != http.StatusOK {
+		t.Errorf("GET /metrics = %d, want 200", code)
+	}
+}
+
+func TestShutdownIsIdempotent(t *testing.T) {
+	var calls int
+	a := newTestApp()
+	a.OnShutdown("db", func(ctx context.Context) error {
+		calls++
+		return errors.New("close failed")
+	})
+	srv, _ := startServer(t, a)
+
+	first := srv.Shutdown(context.Background())
+	second := srv.Shutdown(context.Background())
+	if first == nil || first != second {
+		t.Errorf("Shutdown = %v then %v, want the same error twice", first, second)
+	}
+	if calls != 1 {
+		t.Errorf("shutdown hook ran %d times, want 1", calls)
+	}
+}
+
+func TestRunContextStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := newTestApp()
+	ready := make(chan struct{})
+	a.OnReady("ready", func(context.Context) error {
+		close(ready)
+		return nil
+	})
+
+	srv := &Server{App: a}
+	done := make(chan error, 1)
+	go func() { done <- srv.RunContext(ctx) }()
+
+	select {
+	case <-ready:
+	case err := <-done:
+		t.Fatalf("RunContext returned early: %v", err)
+	}
+	addr := "http://" + srv.Addr().String()
+	if code := getStatus(addr + "/orders"); code != http.StatusOK {
+		t.Errorf("GET /orders = %d, want 200", code)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("RunContext = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("RunContext did not return after ctx was cancelled")
+	}
+	if code := getStatus(addr + "/orders"); code != 0 {
+		t.Errorf("request after RunContext returned = %d, want connection error", code)
+	}
+}
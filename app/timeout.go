@@ -0,0 +1,125 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CodeTimeout is the error code of 504 Gateway Timeout responses
+const CodeTimeout = "timeout"
+
+// GatewayTimeoutError returns a 504 Gateway Timeout error
+func GatewayTimeoutError(message string) *APIError {
+	return NewAPIError(http.StatusGatewayTimeout, CodeTimeout, message)
+}
+
+// Timeout returns a middleware that sets a deadline of d on the request
+// context. Handlers should pass the context to anything that blocks (database
+// queries, outgoing HTTP calls, ...) so work is cancelled when the deadline
+// passes.
+//
+// Like http.TimeoutHandler, the handler's response is buffered and only sent
+// once it returns. If the deadline passes first, the client gets a 504 Gateway
+// Timeout JSON error right away and later writes by the handler fail with
+// http.ErrHandlerTimeout. Handlers that stream responses (http.Flusher) or
+// hijack connections must not run behind it.
+//
+// Use it per route group:
+//
+//	r.Group(func(r chi.Router) {
+//	    r.Use(app.Timeout(5 * time.Second))
+//	    r.Get("/reports", handleReports)
+//	})
+func Timeout(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			// Resolved before the handler runs, as routing in the handler
+			// goroutine may still change the route context afterwards
+			route := routePattern(r)
+
+			// The handler gets its own request, as writing the error
+			// below modifies r
+			hr := r.WithContext(ctx)
+			tw := &timeoutWriter{header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, hr)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				dst := w.Header()
+				for key, values := range tw.header {
+					dst[key] = values
+				}
+				if tw.code == 0 {
+					tw.code = http.StatusOK
+				}
+				w.WriteHeader(tw.code)
+				w.Write(tw.buf.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					slog.Warn("Request timed out", "method", r.Method, "path", r.URL.Path, "route", route, "timeout", d)
+					writeError(w, r, GatewayTimeoutError("request timed out"))
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers a handler's response for Timeout
+type timeoutWriter struct {
+	header http.Header
+
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	code     int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.code == 0 {
+		tw.code = http.StatusOK
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.code != 0 {
+		return
+	}
+	tw.code = code
+}
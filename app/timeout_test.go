@@ -0,0 +1,69 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeoutIgnoringContext(t *testing.T) {
+	lateWrite := make(chan error, 1)
+	h := Timeout(50 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		_, err := w.Write([]byte("late ok"))
+		lateWrite <- err
+	}))
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/reports", nil))
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("responded after %s, want at the 50ms deadline", elapsed)
+	}
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want 504", w.Code)
+	}
+	var body APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Code != CodeTimeout {
+		t.Errorf("body = %s, want JSON error with code %q", w.Body, CodeTimeout)
+	}
+
+	if err := <-lateWrite; err != http.ErrHandlerTimeout {
+		t.Errorf("late write error = %v, want http.ErrHandlerTimeout", err)
+	}
+	if w.Body.Len() == 0 || w.Code != http.StatusGatewayTimeout {
+		t.Errorf("late write changed the response: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestTimeoutPassesResponseThrough(t *testing.T) {
+	h := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); !ok {
+			t.Error("request context has no deadline")
+		}
+		w.Header().Set("X-Report", "1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/reports", nil))
+	if w.Code != http.StatusCreated || w.Header().Get("X-Report") != "1" || w.Body.String() != "created" {
+		t.Errorf("response = %d %v %q, want the handler's", w.Code, w.Header(), w.Body)
+	}
+}
+
+func TestTimeoutPropagatesPanics(t *testing.T) {
+	h := Recoverer(Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/reports", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500 from the recoverer", w.Code)
+	}
+}
@@ -0,0 +1,184 @@
+package app
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// TLS versions accepted by TLSConfig.MinVersion
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Enabled reports whether TLS is configured
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// validate checks the TLS configuration without loading the certificate
+func (c TLSConfig) validate() error {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return fmt.Errorf("invalid TLS config: both cert file and key file are required")
+	}
+	if _, err := parseTLSVersion(c.MinVersion); err != nil {
+		return err
+	}
+	if _, err := parseCipherSuites(c.CipherSuites); err != nil {
+		return err
+	}
+	clientAuth, err := tlsClientAuth(c.ClientAuth, c.ClientCAFile)
+	if err != nil {
+		return err
+	}
+	if clientAuth != tls.NoClientCert && c.ClientCAFile == "" {
+		return fmt.Errorf("invalid TLS config: client auth %q requires a client CA file", c.ClientAuth)
+	}
+	if c.RedirectPort < 0 || c.RedirectPort > 65535 {
+		return fmt.Errorf("invalid TLS redirect port: %d (must be between 1 and 65535, or 0 to disable)", c.RedirectPort)
+	}
+	return nil
+}
+
+// parseTLSVersion parses "1.2" or "1.3" (default "1.2")
+func parseTLSVersion(s string) (uint16, error) {
+	if s == "" {
+		return tls.VersionTLS12, nil
+	}
+	v, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(s), "tls")]
+	if !ok {
+		return 0, fmt.Errorf("invalid TLS min version: %s (must be 1.2 or 1.3)", s)
+	}
+	return v, nil
+}
+
+// parseCipherSuites maps cipher suite names such as
+// "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256" to their IDs. Only suites Go
+// considers secure are accepted. An empty list selects Go's defaults.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, cs := range tls.CipherSuites() {
+		known[cs.Name] = cs.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid TLS cipher suite: %s", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// newTLSConfig creates the tls.Config of the main server. Certificates are
+// served by the reloader.
+func newTLSConfig(cfg TLSConfig, certs *certReloader) (*tls.Config, error) {
+	minVersion, err := parseTLSVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	cipherSuites, err := parseCipherSuites(cfg.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	clientAuth, err := tlsClientAuth(cfg.ClientAuth, cfg.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites, // ignored for TLS 1.3
+		GetCertificate: certs.GetCertificate,
+		ClientAuth:     clientAuth,
+	}
+	if cfg.ClientCAFile != "" {
+		if tlsConfig.ClientCAs, err = loadCertPool(cfg.ClientCAFile); err != nil {
+			return nil, err
+		}
+	}
+	return tlsConfig, nil
+}
+
+// certReloader serves a certificate loaded from disk and reloads it when the
+// certificate or key file changes, so renewed certificates (e.g. from
+// cert-manager or certbot) are picked up without a restart. If reloading
+// fails, the previous certificate is kept.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	cert    atomic.Pointer[tls.Certificate]
+	watcher *fileWatcher
+}
+
+// newCertReloader loads the key pair and starts watching the files
+func newCertReloader(certFile, keyFile string, interval time.Duration) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	c.watcher = watchFiles(interval, func() {
+		if err := c.reload(); err != nil {
+			slog.Error("Failed to reload TLS certificate, keeping the previous one", "cert_file", certFile, "err", err)
+			return
+		}
+		slog.Info("TLS certificate reloaded", "cert_file", certFile)
+	}, certFile, keyFile)
+	return c, nil
+}
+
+func (c *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS key pair: %w", err)
+	}
+	c.cert.Store(&cert)
+	return nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return c.cert.Load(), nil
+}
+
+// close stops watching the files
+func (c *certReloader) close() {
+	if c.watcher != nil {
+		c.watcher.stopWatching()
+	}
+}
+
+// httpsRedirectHandler redirects plain HTTP requests to the same URL on the
+// HTTPS port. 308 is used so clients keep the method and body.
+func httpsRedirectHandler(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,28 @@
+package app
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestParseTLSVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    uint16
+		wantErr bool
+	}{
+		{"", tls.VersionTLS12, false},
+		{"1.2", tls.VersionTLS12, false},
+		{"1.3", tls.VersionTLS13, false},
+		{"TLS1.3", tls.VersionTLS13, false},
+		{"1.0", 0, true},
+		{"1.1", 0, true},
+		{"2.0", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseTLSVersion(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseTLSVersion(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
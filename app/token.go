@@ -0,0 +1,71 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+)
+
+// TokenExtractor extracts a raw credential (API key or token) from a request.
+// It returns "" if the request does not carry a credential in that location.
+type TokenExtractor func(r *http.Request) string
+
+// TokenFromHeader extracts the whole value of the named header, trimmed of whitespace
+func TokenFromHeader(header string) TokenExtractor {
+	return func(r *http.Request) string {
+		token, _ := apiToken(r, header)
+		return token
+	}
+}
+
+// TokenFromBearer extracts the token from an "Authorization: Bearer <token>" header
+func TokenFromBearer() TokenExtractor {
+	return func(r *http.Request) string {
+		token, err := bearerToken(r, "Authorization")
+		if err != nil {
+			return ""
+		}
+		return token
+	}
+}
+
+// TokenFromAuthScheme extracts the credentials from an
+// "Authorization: <scheme> <credentials>" header. The scheme is matched
+// case-insensitively; headers with other schemes are ignored.
+func TokenFromAuthScheme(scheme string) TokenExtractor {
+	return func(r *http.Request) string {
+		token, err := schemeToken(r, "Authorization", scheme)
+		if err != nil {
+			return ""
+		}
+		return token
+	}
+}
+
+// TokenFromQuery extracts the token from the named URL query parameter.
+// Query strings often end up in access logs, so prefer headers where possible.
+func TokenFromQuery(param string) TokenExtractor {
+	return func(r *http.Request) string {
+		return strings.TrimSpace(r.URL.Query().Get(param))
+	}
+}
+
+// TokenFromCookie extracts the token from the named cookie
+func TokenFromCookie(name string) TokenExtractor {
+	return func(r *http.Request) string {
+		cookie, err := r.Cookie(name)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(cookie.Value)
+	}
+}
+
+// extractToken tries each extractor in order and returns the first non-empty token
+func extractToken(r *http.Request, extractors []TokenExtractor) string {
+	for _, extract := range extractors {
+		if token := extract(r); token != "" {
+			return token
+		}
+	}
+	return ""
+}
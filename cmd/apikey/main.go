@@ -2,6 +2,7 @@ package main
 
 import (
 	"net/http"
+	"os"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -10,7 +11,7 @@ import (
 )
 
 func main() {
-	apiApp := app.Default()
+	apiApp := app.DefaultApp()
 
 	apiKeyConfig := app.ApiKeyConfig{
 		APIKeys: map[string]string{
@@ -27,12 +28,16 @@ func main() {
 				r.Get("/", handleApi)
 			})
 		})
-		apiApp.Run()
+		if err := apiApp.Run(); err != nil {
+			slog.Error("Server failed", "err", err)
+			os.Exit(1)
+		}
 	}
 }
 
 // curl -i -H "Authorization: abc" localhost:4000/api
 
 func handleApi(w http.ResponseWriter, r *http.Request) {
-	render.PlainText(w, r, "API OK!")
+	principal, _ := app.PrincipalFromContext(r.Context())
+	render.PlainText(w, r, "API OK! Hello, "+principal)
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"log/slog"
 	"net/http"
+	"os"
 
 	"github.com/go-chi/render"
 	"github.com/tendant/chi-demo/app"
@@ -28,5 +29,8 @@ func main() {
 
 	// Start the server (blocks until shutdown signal)
 	slog.Info("Starting basic example on http://localhost:3000")
-	myApp.Run()
+	if err := myApp.Run(); err != nil {
+		slog.Error("Server failed", "err", err)
+		os.Exit(1)
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
 
 	"github.com/ggicci/httpin"
 	"github.com/go-chi/render"
@@ -32,11 +33,20 @@ func main() {
 	// Form data
 	myApp.R.With(httpin.NewInput(FormInput{})).Post("/form", HandleForm)
 
-	// JSON body
-	myApp.R.With(httpin.NewInput(JSONInput{})).Post("/json", HandleJSON)
+	// JSON body, limited to 64 KiB of application/json
+	myApp.R.With(
+		app.RequestBody(app.RequestBodyConfig{
+			MaxBytes:     64 << 10,
+			ContentTypes: []string{"application/json"},
+		}),
+		httpin.NewInput(JSONInput{}),
+	).Post("/json", HandleJSON)
 
 	slog.Info("Input handling examples available at http://localhost:3000")
-	myApp.Run()
+	if err := myApp.Run(); err != nil {
+		slog.Error("Server failed", "err", err)
+		os.Exit(1)
+	}
 }
 
 // QueryInput demonstrates basic query parameter parsing
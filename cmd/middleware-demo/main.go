@@ -69,7 +69,7 @@ func main() {
 	// Register routes
 	app.RegisterDefaultRoutes(myApp.R)
 	app.RegisterVersionRoutes(myApp.R)
-	app.RegisterHealthzRoutes(myApp.R)
+	myApp.RegisterHealthzRoutes()
 
 	myApp.R.Get("/hello", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Hello! Your middleware stack is working!\n"))
@@ -7,6 +7,7 @@ require (
 	github.com/go-chi/chi/v5 v5.2.3
 	github.com/go-chi/httplog/v2 v2.1.1
 	github.com/go-chi/render v1.0.3
+	github.com/golang-jwt/jwt/v5 v5.3.1
 	github.com/google/uuid v1.6.0
 	github.com/ilyakaznacheev/cleanenv v1.5.0
 	github.com/lmittmann/tint v1.1.2
@@ -15,6 +16,7 @@ require (
 	github.com/slok/go-http-metrics v0.13.0
 	github.com/tendant/cors v1.3.1
 	golang.org/x/exp v0.0.0-20251023183803-a4bb9ffd2546
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -41,6 +43,5 @@ require (
 	golang.org/x/sys v0.37.0 // indirect
 	golang.org/x/text v0.30.0 // indirect
 	google.golang.org/protobuf v1.36.10 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 	olympos.io/encoding/edn v0.0.0-20201019073823-d3554ca0b0a3 // indirect
 )